@@ -0,0 +1,371 @@
+// Package apu implements the NES's Ricoh 2A03 apu
+package apu
+
+import (
+	"github.com/m4ntis/bones/region"
+)
+
+const (
+	// sampleBatchSize is the minimal amount of samples sent to the speaker at
+	// once
+	sampleBatchSize = 512
+
+	// blipFrameLen is the amount of cycles after which the output's samples
+	// are read from the blip buffer
+	blipFrameLen = 1024
+)
+
+const (
+	pulse1Addr       = 0x4000
+	pulse2Addr       = 0x4004
+	triangleAddr     = 0x4008
+	noiseAddr        = 0x400c
+	dmcAddr          = 0x4010
+	statusAddr       = 0x4015
+	frameCounterAddr = 0x4017
+)
+
+// Speaker describes a place that the APU outputs its audio samples to.
+type Speaker interface {
+	// SampleRate returns the rate, in Hz, at which the speaker expects to
+	// receive samples.
+	SampleRate() int
+
+	// Play receives a batch of mono samples, ranging from -1 to 1.
+	Play(samples []float32)
+}
+
+// RateController is an optional interface a Speaker can implement to control
+// the rate at which it receives samples, such as real time speakers keeping
+// their buffer at a target fill level.
+type RateController interface {
+	// RateRatio returns the factor by which the speaker's sample rate should
+	// currently be scaled. It is queried after every batch of samples played.
+	RateRatio() float64
+}
+
+// Memory describes the CPU address space the DMC channel fetches its sample
+// bytes from.
+type Memory interface {
+	Read(addr int) (byte, error)
+}
+
+// APU implements the Ricoh 2A03's audio processing unit.
+//
+// The APU is clocked once per CPU cycle, and exposes its registers via Write
+// and StatusRead, as they are memory mapped on the CPU RAM.
+//
+// Mem should be set to the CPU's RAM before running the APU, as the DMC reads
+// its samples from it.
+type APU struct {
+	Mem Memory
+
+	pulse1   *pulse
+	pulse2   *pulse
+	triangle *triangle
+	noise    *noise
+	dmc      *dmc
+
+	frameCounter *frameCounter
+
+	// clockRate is the CPU's clock rate in Hz, which the APU runs at
+	clockRate float64
+
+	// exp is the cartridge's expansion sound chip, mixed in at expLevel
+	exp      AudioExpansion
+	expLevel float32
+
+	// channels holds the channels mixed into the output
+	channels Channel
+
+	// Pulse channels are clocked every other cpu cycle
+	oddCycle bool
+
+	// Output
+	spk        Speaker
+	rc         RateController
+	blip       *BlipBuffer
+	filters    filterChain
+	samples    []float32
+	lastOutput float32
+
+	// frameSamples is a scratch buffer the blip buffer's samples are read into
+	frameSamples []float32
+	frameClock   int
+}
+
+// New initializes an APU instance and returns it.
+//
+// spk is where the APU outputs its samples to. If spk is nil, no samples are
+// generated.
+func New(spk Speaker) *APU {
+	a := &APU{
+		pulse1:   newPulse(onesComplement),
+		pulse2:   newPulse(twosComplement),
+		triangle: &triangle{},
+		noise:    newNoise(),
+		dmc:      newDMC(),
+
+		frameCounter: &frameCounter{steps: &ntscFrameSteps},
+
+		clockRate: region.NTSC.CPUClockRate(),
+
+		channels: AllChannels,
+
+		spk: spk,
+	}
+
+	if spk != nil {
+		rate := float64(spk.SampleRate())
+
+		a.blip = NewBlipBuffer(a.clockRate, rate)
+		a.filters = newFilterChain(rate)
+		a.samples = make([]float32, 0, sampleBatchSize)
+		a.frameSamples = make([]float32, blipFrameLen)
+
+		a.rc, _ = spk.(RateController)
+	}
+
+	return a
+}
+
+// Cycle executes a single APU cycle, which runs at the CPU's clock rate.
+func (a *APU) Cycle() {
+	a.triangle.clockTimer()
+	a.noise.clockTimer()
+	a.dmc.clockTimer(a.Mem)
+
+	if a.exp != nil {
+		a.exp.ClockAudio()
+	}
+
+	if a.oddCycle {
+		a.pulse1.clockTimer()
+		a.pulse2.clockTimer()
+	}
+	a.oddCycle = !a.oddCycle
+
+	quarter, half := a.frameCounter.cycle()
+	if quarter {
+		a.clockQuarterFrame()
+	}
+	if half {
+		a.clockHalfFrame()
+	}
+
+	if a.spk != nil {
+		a.sample()
+	}
+}
+
+// FrameIRQ returns whether the frame counter is asserting its interrupt. The
+// interrupt stays asserted until acknowledged by reading the status register.
+func (a *APU) FrameIRQ() bool {
+	return a.frameCounter.irq
+}
+
+// DMCIRQ returns whether the DMC is asserting its interrupt. The interrupt stays
+// asserted until acknowledged by writing to the status register.
+func (a *APU) DMCIRQ() bool {
+	return a.dmc.irq
+}
+
+// DMAStall returns the amount of CPU cycles the DMC's sample fetches stalled
+// the CPU for since the last call.
+//
+// The CPU should add the stalled cycles to its cycle count, as the DMC's
+// fetches take over the CPU's bus.
+func (a *APU) DMAStall() (cycles int) {
+	cycles = a.dmc.stall
+	a.dmc.stall = 0
+	return cycles
+}
+
+// SetChannels sets the channels mixed into the APU's output, allowing to
+// isolate specific channels. All channels are mixed by default.
+func (a *APU) SetChannels(ch Channel) {
+	a.channels = ch
+}
+
+// Channels returns the channels currently mixed into the APU's output.
+func (a *APU) Channels() Channel {
+	return a.channels
+}
+
+// Mute removes ch from the APU's output.
+func (a *APU) Mute(ch Channel) {
+	a.channels &^= ch
+}
+
+// Unmute mixes ch back into the APU's output.
+func (a *APU) Unmute(ch Channel) {
+	a.channels |= ch
+}
+
+// Solo mutes all channels except for ch.
+func (a *APU) Solo(ch Channel) {
+	a.channels = ch
+}
+
+// Output returns the current mixed output level of all channels. The APU's
+// channels range from 0 to 1, with the expansion chip's output added on top.
+func (a *APU) Output() float32 {
+	var pulse1, pulse2, triangle, noise, dmc byte
+
+	if a.channels&Pulse1 != 0 {
+		pulse1 = a.pulse1.output()
+	}
+	if a.channels&Pulse2 != 0 {
+		pulse2 = a.pulse2.output()
+	}
+	if a.channels&Triangle != 0 {
+		triangle = a.triangle.output()
+	}
+	if a.channels&Noise != 0 {
+		noise = a.noise.output()
+	}
+	if a.channels&DMC != 0 {
+		dmc = a.dmc.output()
+	}
+
+	return mix(pulse1, pulse2, triangle, noise, dmc) + a.expansionOutput()
+}
+
+// sample adds the change in the output level to the blip buffer. Every
+// blipFrameLen cycles the buffer's samples are passed through the output
+// filters and sent to the speaker in batches of at least sampleBatchSize.
+func (a *APU) sample() {
+	out := a.Output()
+	if out != a.lastOutput {
+		a.blip.AddDelta(a.frameClock, out-a.lastOutput)
+		a.lastOutput = out
+	}
+
+	a.frameClock++
+	if a.frameClock < blipFrameLen {
+		return
+	}
+
+	a.blip.EndFrame(a.frameClock)
+	a.frameClock = 0
+
+	n := a.blip.ReadSamples(a.frameSamples)
+	for _, sample := range a.frameSamples[:n] {
+		a.samples = append(a.samples, a.filters.apply(sample))
+	}
+
+	if len(a.samples) >= sampleBatchSize {
+		a.spk.Play(a.samples)
+		a.samples = make([]float32, 0, sampleBatchSize)
+
+		if a.rc != nil {
+			a.setBlipRates()
+		}
+	}
+}
+
+// setBlipRates sets the blip buffer's rates to the APU's clock rate and the
+// speaker's sample rate, scaled by the speaker's current rate ratio if it is
+// a RateController.
+func (a *APU) setBlipRates() {
+	rate := float64(a.spk.SampleRate())
+	if a.rc != nil {
+		rate *= a.rc.RateRatio()
+	}
+
+	a.blip.SetRates(a.clockRate, rate)
+}
+
+// Write writes a value to one of the APU's registers, $4000-$4013, $4015 and
+// $4017.
+func (a *APU) Write(addr int, d byte) {
+	switch {
+	case addr == statusAddr:
+		a.statusWrite(d)
+	case addr == frameCounterAddr:
+		a.frameCounterWrite(d)
+	case addr >= dmcAddr:
+		a.dmc.write(addr-dmcAddr, d)
+	case addr >= noiseAddr:
+		a.noise.write(addr-noiseAddr, d)
+	case addr >= triangleAddr:
+		a.triangle.write(addr-triangleAddr, d)
+	case addr >= pulse2Addr:
+		a.pulse2.write(addr-pulse2Addr, d)
+	case addr >= pulse1Addr:
+		a.pulse1.write(addr-pulse1Addr, d)
+	}
+}
+
+// StatusRead returns the value of the status register ($4015), containing the
+// channels' length counter status and the interrupt flags.
+//
+// Reading the status register acknowledges the frame interrupt.
+func (a *APU) StatusRead() (d byte) {
+	if a.pulse1.length.value > 0 {
+		d |= 1
+	}
+	if a.pulse2.length.value > 0 {
+		d |= 1 << 1
+	}
+	if a.triangle.length.value > 0 {
+		d |= 1 << 2
+	}
+	if a.noise.length.value > 0 {
+		d |= 1 << 3
+	}
+	if a.dmc.bytesRemaining > 0 {
+		d |= 1 << 4
+	}
+	if a.frameCounter.irq {
+		d |= 1 << 6
+	}
+	if a.dmc.irq {
+		d |= 1 << 7
+	}
+
+	a.frameCounter.irq = false
+	return d
+}
+
+// statusWrite enables and disables the channels and acknowledges the DMC
+// interrupt.
+func (a *APU) statusWrite(d byte) {
+	a.pulse1.length.setEnabled(d&1 == 1)
+	a.pulse2.length.setEnabled(d>>1&1 == 1)
+	a.triangle.length.setEnabled(d>>2&1 == 1)
+	a.noise.length.setEnabled(d>>3&1 == 1)
+	a.dmc.setEnabled(d>>4&1 == 1)
+
+	a.dmc.irq = false
+}
+
+// frameCounterWrite sets the frame counter's mode and interrupt inhibit flag.
+//
+// Setting the 5-step mode immediately clocks the quarter and half frame units.
+func (a *APU) frameCounterWrite(d byte) {
+	a.frameCounter.write(d)
+
+	if a.frameCounter.fiveStep {
+		a.clockQuarterFrame()
+		a.clockHalfFrame()
+	}
+}
+
+// clockQuarterFrame clocks the envelopes and the triangle's linear counter.
+func (a *APU) clockQuarterFrame() {
+	a.pulse1.env.clock()
+	a.pulse2.env.clock()
+	a.triangle.clockLinearCounter()
+	a.noise.env.clock()
+}
+
+// clockHalfFrame clocks the length counters and sweep units.
+func (a *APU) clockHalfFrame() {
+	a.pulse1.length.clock()
+	a.pulse1.clockSweep()
+	a.pulse2.length.clock()
+	a.pulse2.clockSweep()
+	a.triangle.length.clock()
+	a.noise.length.clock()
+}
@@ -0,0 +1,168 @@
+package apu
+
+import (
+	"testing"
+
+	"github.com/m4ntis/bones/region"
+)
+
+func TestMuteSolo(t *testing.T) {
+	a := New(nil)
+
+	// The DMC's direct load sets its output level without clocking the APU.
+	// The other channels are muted, as the triangle holds its output level
+	// while silent.
+	a.Write(dmcAddr+1, 0x40)
+	a.Solo(DMC)
+	dmcOut := a.Output()
+	if dmcOut == 0 {
+		t.Fatal("DMC output is silent after a direct load")
+	}
+
+	tests := []struct {
+		name  string
+		apply func()
+		want  float32
+	}{
+		{"mute dmc", func() { a.Mute(DMC) }, 0},
+		{"unmute dmc", func() { a.Unmute(DMC) }, dmcOut},
+		{"solo pulse1", func() { a.Solo(Pulse1) }, 0},
+		{"unmute dmc while soloing", func() { a.Unmute(DMC) }, dmcOut},
+		{"solo dmc", func() { a.Solo(DMC) }, dmcOut},
+		{"mute pulse1", func() { a.Mute(Pulse1) }, dmcOut},
+		{"mute all", func() { a.Mute(AllChannels) }, 0},
+		{"unmute dmc and pulse2", func() { a.Unmute(DMC | Pulse2) }, dmcOut},
+	}
+
+	for _, test := range tests {
+		test.apply()
+		if out := a.Output(); out != test.want {
+			t.Errorf("%s: output is %v, want %v", test.name, out, test.want)
+		}
+	}
+
+	a.Solo(Pulse2 | Noise)
+	if ch := a.Channels(); ch != Pulse2|Noise {
+		t.Errorf("Channels after soloing pulse2 and noise are %v", ch)
+	}
+}
+
+func TestChannelStates(t *testing.T) {
+	a := New(nil)
+
+	// Enable the channels with length counters, which are then loaded by
+	// writing the period's high bits
+	a.Write(statusAddr, 0x0f)
+
+	// Duty 2, halt and constant volume of 15, period $234, length index 1
+	a.Write(pulse1Addr, 0xbf)
+	a.Write(pulse1Addr+2, 0x34)
+	a.Write(pulse1Addr+3, 0x0a)
+
+	// Envelope with a decay period of 4, period $123, length index 1. The
+	// envelope only starts decaying on the frame counter's next clock.
+	a.Write(pulse2Addr, 0x44)
+	a.Write(pulse2Addr+2, 0x23)
+	a.Write(pulse2Addr+3, 0x09)
+
+	// Period $310, length index 1
+	a.Write(triangleAddr, 0x81)
+	a.Write(triangleAddr+2, 0x10)
+	a.Write(triangleAddr+3, 0x0b)
+
+	// Halt and constant volume of 5, period index 3, length index 1
+	a.Write(noiseAddr, 0x35)
+	a.Write(noiseAddr+2, 0x03)
+	a.Write(noiseAddr+3, 0x08)
+
+	// Rate index 15 and direct load of $40
+	a.Write(dmcAddr, 0x0f)
+	a.Write(dmcAddr+1, 0x40)
+
+	states := a.ChannelStates()
+	want := []ChannelState{
+		{
+			Channel: Pulse1,
+			Period:  0x234,
+			Volume:  15,
+			Duty:    2,
+			Envelope: EnvelopeState{
+				Constant: true,
+				Loop:     true,
+				Period:   15,
+			},
+			Length: 254,
+		},
+		{
+			Channel:  Pulse2,
+			Period:   0x123,
+			Duty:     1,
+			Envelope: EnvelopeState{Period: 4},
+			Length:   254,
+		},
+		{
+			Channel: Triangle,
+			Period:  0x310,
+			Length:  254,
+		},
+		{
+			Channel:  Noise,
+			Period:   noiseTable[3],
+			Volume:   5,
+			Envelope: EnvelopeState{Constant: true, Loop: true, Period: 5},
+			Length:   254,
+		},
+		{
+			Channel: DMC,
+			Period:  dmcTable[15],
+			Volume:  0x40,
+			Output:  0x40,
+		},
+	}
+
+	if len(states) != len(want) {
+		t.Fatalf("Got %d channel states, want %d", len(states), len(want))
+	}
+	for i := range want {
+		// The channels' output depends on their sequencers' positions, and
+		// is only checked for the DMC
+		if want[i].Channel != DMC {
+			states[i].Output = 0
+		}
+
+		if states[i] != want[i] {
+			t.Errorf("Channel %v state is\n%+v, want\n%+v", want[i].Channel,
+				states[i], want[i])
+		}
+	}
+}
+
+// testRateSpeaker is a Speaker controlling its rate by a fixed ratio.
+type testRateSpeaker struct {
+	ratio float64
+}
+
+func (s *testRateSpeaker) SampleRate() int {
+	return 48000
+}
+
+func (s *testRateSpeaker) Play([]float32) {}
+
+func (s *testRateSpeaker) RateRatio() float64 {
+	return s.ratio
+}
+
+func TestSetRegionRateRatio(t *testing.T) {
+	spk := &testRateSpeaker{ratio: 1.005}
+	a := New(spk)
+
+	for _, r := range []region.Region{region.PAL, region.Dendy, region.NTSC} {
+		a.SetRegion(r)
+
+		want := 48000 * spk.ratio / r.CPUClockRate()
+		if a.blip.factor != want {
+			t.Errorf("%v: %v samples per clock, want %v", r, a.blip.factor,
+				want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package apu
+
+import (
+	"math"
+)
+
+const (
+	// blipPhases is the amount of sub-sample positions a step can be placed at
+	blipPhases = 32
+	// blipWidth is the amount of output samples a single step is spread over
+	blipWidth = 16
+	// blipCutoff is the kernel's cutoff frequency relative to the output
+	// sample rate's nyquist frequency
+	blipCutoff = 0.9
+)
+
+// blipKernel holds the band-limited impulse for each sub-sample phase, spread
+// over blipWidth samples.
+var blipKernel = genBlipKernel()
+
+// BlipBuffer is a band-limited step synthesizer, used for resampling a signal
+// running at a high clock rate (such as the APU's output) down to a host's
+// sample rate without aliasing.
+//
+// Instead of sampling the signal, the caller adds the signal's changes
+// (deltas) at the clock they occured on. Each delta is added to the buffer as
+// a band-limited step, which is then integrated back into samples when read.
+//
+// Time is divided into frames. Deltas are added relative to the beginning of
+// the current frame, and EndFrame makes the samples of a frame available for
+// reading.
+type BlipBuffer struct {
+	// factor is the amount of output samples per clock
+	factor float64
+	// offset is the position, in output samples, of the current frame's
+	// beginning
+	offset float64
+
+	buf   []float32
+	integ float32
+}
+
+// NewBlipBuffer creates a BlipBuffer resampling a signal from clockRate to
+// sampleRate, both in Hz.
+func NewBlipBuffer(clockRate, sampleRate float64) *BlipBuffer {
+	b := &BlipBuffer{}
+	b.SetRates(clockRate, sampleRate)
+
+	return b
+}
+
+// SetRates changes the buffer's clock and sample rate. Changing the rates
+// affects deltas added from this point on.
+func (b *BlipBuffer) SetRates(clockRate, sampleRate float64) {
+	b.factor = sampleRate / clockRate
+}
+
+// AddDelta adds a change in the signal's amplitude at a given clock, relative
+// to the beginning of the current frame.
+func (b *BlipBuffer) AddDelta(clock int, delta float32) {
+	pos := b.offset + float64(clock)*b.factor
+
+	i := int(pos)
+	phase := int((pos - float64(i)) * blipPhases)
+
+	b.grow(i + blipWidth)
+	kernel := &blipKernel[phase]
+	for k := 0; k < blipWidth; k++ {
+		b.buf[i+k] += delta * kernel[k]
+	}
+}
+
+// EndFrame ends the current frame after a given amount of clocks, making its
+// samples available for reading.
+func (b *BlipBuffer) EndFrame(clocks int) {
+	b.offset += float64(clocks) * b.factor
+	b.grow(int(b.offset) + blipWidth)
+}
+
+// Available returns the amount of samples ready to be read.
+func (b *BlipBuffer) Available() int {
+	return int(b.offset)
+}
+
+// ReadSamples reads up to len(out) available samples into out, removing them
+// from the buffer and returning the amount of samples read.
+func (b *BlipBuffer) ReadSamples(out []float32) (n int) {
+	n = b.Available()
+	if len(out) < n {
+		n = len(out)
+	}
+
+	for i := 0; i < n; i++ {
+		b.integ += b.buf[i]
+		out[i] = b.integ
+	}
+
+	// Shift the remaining deltas to the beginning of the buffer
+	remaining := copy(b.buf, b.buf[n:])
+	for i := remaining; i < len(b.buf); i++ {
+		b.buf[i] = 0
+	}
+	b.offset -= float64(n)
+
+	return n
+}
+
+// grow makes sure the buffer can hold size samples.
+func (b *BlipBuffer) grow(size int) {
+	if size > len(b.buf) {
+		b.buf = append(b.buf, make([]float32, size-len(b.buf))...)
+	}
+}
+
+// genBlipKernel generates a Blackman windowed sinc for each of the sub-sample
+// phases. Each phase is normalized to sum up to 1, so that a step of a given
+// delta integrates to exactly that delta.
+func genBlipKernel() (kernel [blipPhases][blipWidth]float32) {
+	for p := 0; p < blipPhases; p++ {
+		var sum float64
+		var taps [blipWidth]float64
+
+		for k := 0; k < blipWidth; k++ {
+			// Distance in samples between the tap and the step's position
+			x := float64(k-blipWidth/2+1) - float64(p)/blipPhases
+
+			sinc := 1.0
+			if x != 0 {
+				sinc = math.Sin(math.Pi*blipCutoff*x) / (math.Pi * blipCutoff * x)
+			}
+
+			// Blackman window over the kernel's width
+			w := (x + blipWidth/2) / blipWidth
+			window := 0.42 - 0.5*math.Cos(2*math.Pi*w) + 0.08*math.Cos(4*math.Pi*w)
+
+			taps[k] = sinc * window
+			sum += taps[k]
+		}
+
+		for k := range taps {
+			kernel[p][k] = float32(taps[k] / sum)
+		}
+	}
+
+	return kernel
+}
@@ -0,0 +1,124 @@
+package apu
+
+import (
+	"math"
+	"testing"
+)
+
+const (
+	testClockRate  = 1789773
+	testSampleRate = 44100
+	// testFrameClocks is about a frame's worth of CPU clocks
+	testFrameClocks = 29780
+)
+
+func TestBlipBufferSampleCount(t *testing.T) {
+	tests := []struct {
+		clockRate  float64
+		sampleRate float64
+		frames     int
+	}{
+		{testClockRate, testSampleRate, 1},
+		{testClockRate, testSampleRate, 60},
+		{testClockRate, 48000, 60},
+		{1662607, testSampleRate, 50},
+	}
+
+	for _, test := range tests {
+		b := NewBlipBuffer(test.clockRate, test.sampleRate)
+		out := make([]float32, 4096)
+
+		read := 0
+		for f := 0; f < test.frames; f++ {
+			b.AddDelta(testFrameClocks/2, 0.5)
+			b.EndFrame(testFrameClocks)
+			read += b.ReadSamples(out)
+		}
+
+		// Fractions of samples are carried over to the next frame
+		want := int(float64(test.frames*testFrameClocks) * test.sampleRate /
+			test.clockRate)
+		if read != want {
+			t.Errorf("%v Hz -> %v Hz, %d frames: read %d samples, want %d",
+				test.clockRate, test.sampleRate, test.frames, read, want)
+		}
+	}
+}
+
+func TestBlipBufferStep(t *testing.T) {
+	b := NewBlipBuffer(testClockRate, testSampleRate)
+
+	// A step in the middle of the frame
+	b.AddDelta(testFrameClocks/2, 1)
+	b.EndFrame(testFrameClocks)
+
+	out := make([]float32, b.Available())
+	n := b.ReadSamples(out)
+	if n != len(out) {
+		t.Fatalf("Read %d samples, want %d", n, len(out))
+	}
+
+	// The step's kernel starts at the sample the step falls on
+	step := int(testFrameClocks / 2 * testSampleRate / testClockRate)
+
+	// Before the kernel the output is silent, and after it the output
+	// settles on the step's delta
+	for i := 0; i < step; i++ {
+		if out[i] != 0 {
+			t.Fatalf("Sample %d before the step is %v, want 0", i, out[i])
+		}
+	}
+	for i := step + blipWidth; i < n; i++ {
+		if math.Abs(float64(out[i]-1)) > 1e-4 {
+			t.Fatalf("Sample %d after the step is %v, want 1", i, out[i])
+		}
+	}
+
+	// A band-limited step rings on both sides of its edge, which a sampled
+	// step wouldn't, dipping below 0 before it and overshooting 1 after it
+	var lo, hi float32
+	for i := step; i < step+blipWidth; i++ {
+		if out[i] < lo {
+			lo = out[i]
+		}
+		if out[i] > hi {
+			hi = out[i]
+		}
+	}
+	if lo >= 0 || lo < -0.2 {
+		t.Errorf("Step rings down to %v before its edge, want in (-0.2, 0)",
+			lo)
+	}
+	if hi <= 1 || hi > 1.2 {
+		t.Errorf("Step overshoots to %v after its edge, want in (1, 1.2)", hi)
+	}
+}
+
+func TestFilterChainSettles(t *testing.T) {
+	fc := newFilterChain(testSampleRate)
+
+	// The high pass filters block DC, so a constant input settles to 0
+	var out float32
+	for i := 0; i < testSampleRate; i++ {
+		out = fc.apply(1)
+	}
+	if math.Abs(float64(out)) > 1e-3 {
+		t.Errorf("Filtered DC input settles on %v, want 0", out)
+	}
+}
+
+func TestHighPassSettles(t *testing.T) {
+	f := newFilter(true, 90, testSampleRate)
+
+	out := f.apply(1)
+	if out < 0.9 {
+		t.Errorf("High pass output on a step is %v, want about 1", out)
+	}
+
+	for i := 0; i < testSampleRate; i++ {
+		out = f.apply(1)
+	}
+	if math.Abs(float64(out)) > 1e-3 {
+		t.Errorf("High pass output settles on %v, want 0", out)
+	}
+}
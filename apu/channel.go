@@ -0,0 +1,50 @@
+package apu
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Channel identifies one or more of the APU's sound channels, and can be OR'ed
+// together to describe a set of channels.
+type Channel int
+
+const (
+	Pulse1 Channel = 1 << iota
+	Pulse2
+	Triangle
+	Noise
+	DMC
+	// Expansion is the cartridge's expansion sound chip, if it has one
+	Expansion
+
+	AllChannels = Pulse1 | Pulse2 | Triangle | Noise | DMC | Expansion
+)
+
+var channelNames = map[Channel]string{
+	Pulse1:    "pulse1",
+	Pulse2:    "pulse2",
+	Triangle:  "triangle",
+	Noise:     "noise",
+	DMC:       "dmc",
+	Expansion: "expansion",
+}
+
+func (ch Channel) String() string {
+	name, ok := channelNames[ch]
+	if !ok {
+		return "unknown"
+	}
+	return name
+}
+
+// ParseChannel returns the channel named name, which is one of pulse1, pulse2,
+// triangle, noise, dmc and expansion.
+func ParseChannel(name string) (Channel, error) {
+	for ch, chName := range channelNames {
+		if name == chName {
+			return ch, nil
+		}
+	}
+
+	return 0, errors.Errorf("Unknown APU channel '%s'", name)
+}
@@ -0,0 +1,177 @@
+package apu
+
+// dmaStallCycles is the amount of cycles the CPU is halted for while the DMC
+// fetches a sample byte.
+//
+// Depending on the cycle the CPU is halted on, the stall really takes 1 to 4
+// cycles. 4 is the most common case, occuring when the CPU is halted on a read.
+const dmaStallCycles = 4
+
+// DMC timer periods, in CPU cycles
+var (
+	dmcTable = [16]int{
+		428, 380, 340, 320, 286, 254, 226, 214, 190, 160, 142, 128, 106, 84,
+		72, 54,
+	}
+	palDMCTable = [16]int{
+		398, 354, 316, 298, 276, 236, 210, 198, 176, 148, 132, 118, 98, 78,
+		66, 50,
+	}
+)
+
+// dmc implements the APU's delta modulation channel, which plays 1-bit delta
+// encoded samples fetched from the CPU's memory.
+type dmc struct {
+	irqEnabled bool
+	irq        bool
+	loop       bool
+
+	// periods is the region's timer period table
+	periods *[16]int
+
+	period int
+	timer  int
+
+	// Output unit
+	level         byte
+	shift         byte
+	bitsRemaining int
+	silence       bool
+
+	// Memory reader
+	sampleAddr     int
+	sampleLength   int
+	currAddr       int
+	bytesRemaining int
+	buffer         byte
+	bufferEmpty    bool
+
+	// stall is the amount of CPU cycles stalled by sample fetches that weren't
+	// yet accounted for by the CPU
+	stall int
+}
+
+func newDMC() *dmc {
+	return &dmc{
+		periods:       &dmcTable,
+		period:        dmcTable[0],
+		bitsRemaining: 8,
+		silence:       true,
+		bufferEmpty:   true,
+	}
+}
+
+// write sets one of the channel's 4 registers, specified by reg (0 ~ 3).
+func (d *dmc) write(reg int, v byte) {
+	switch reg {
+	case 0:
+		d.irqEnabled = v>>7 == 1
+		d.loop = v>>6&1 == 1
+		d.period = d.periods[v&0xf]
+
+		if !d.irqEnabled {
+			d.irq = false
+		}
+	case 1:
+		d.level = v & 0x7f
+	case 2:
+		d.sampleAddr = 0xc000 + int(v)*64
+	case 3:
+		d.sampleLength = int(v)*16 + 1
+	}
+}
+
+// setEnabled is called when writing to the status register. Enabling the
+// channel restarts the sample only if it has finished playing.
+func (d *dmc) setEnabled(enabled bool) {
+	if !enabled {
+		d.bytesRemaining = 0
+		return
+	}
+
+	if d.bytesRemaining == 0 {
+		d.restart()
+	}
+}
+
+// restart starts playing the sample from its beginning.
+func (d *dmc) restart() {
+	d.currAddr = d.sampleAddr
+	d.bytesRemaining = d.sampleLength
+}
+
+// clockTimer is called every CPU cycle, fetching the next sample byte if
+// needed and clocking the output unit when the timer expires.
+func (d *dmc) clockTimer(mem Memory) {
+	d.fill(mem)
+
+	if d.timer > 0 {
+		d.timer--
+		return
+	}
+
+	d.timer = d.period - 1
+	d.clockOutput()
+}
+
+// clockOutput applies the next delta bit of the shift register to the output
+// level.
+func (d *dmc) clockOutput() {
+	if !d.silence {
+		if d.shift&1 == 1 {
+			if d.level <= 125 {
+				d.level += 2
+			}
+		} else if d.level >= 2 {
+			d.level -= 2
+		}
+	}
+
+	d.shift >>= 1
+	d.bitsRemaining--
+
+	// Start a new output cycle
+	if d.bitsRemaining == 0 {
+		d.bitsRemaining = 8
+
+		if d.bufferEmpty {
+			d.silence = true
+		} else {
+			d.silence = false
+			d.shift = d.buffer
+			d.bufferEmpty = true
+		}
+	}
+}
+
+// fill fetches the next sample byte into the sample buffer if it is empty and
+// there are bytes remaining.
+func (d *dmc) fill(mem Memory) {
+	if !d.bufferEmpty || d.bytesRemaining == 0 || mem == nil {
+		return
+	}
+
+	d.buffer, _ = mem.Read(d.currAddr)
+	d.bufferEmpty = false
+	d.stall += dmaStallCycles
+
+	// The address wraps around to $8000 when overflowing
+	d.currAddr++
+	if d.currAddr > 0xffff {
+		d.currAddr = 0x8000
+	}
+
+	d.bytesRemaining--
+	if d.bytesRemaining == 0 {
+		if d.loop {
+			d.restart()
+		} else if d.irqEnabled {
+			d.irq = true
+		}
+	}
+}
+
+// output returns the channel's current output level (0 ~ 127).
+func (d *dmc) output() byte {
+	return d.level
+}
@@ -0,0 +1,82 @@
+package apu
+
+// ExpansionChip identifies a cartridge expansion sound chip.
+type ExpansionChip int
+
+const (
+	VRC6 ExpansionChip = iota + 1
+	VRC7
+	FDS
+	MMC5
+	Namco163
+	Sunsoft5B
+)
+
+// expansionLevels holds each expansion chip's output level at full scale,
+// relative to the APU's mixed output, so that the chips are mixed in at their
+// hardware relative volume.
+//
+// The levels are approximations of hardware measurements, as described in
+// https://wiki.nesdev.com/w/index.php/Expansion_audio. For reference, a single
+// APU pulse channel at full volume outputs ~0.149.
+var expansionLevels = map[ExpansionChip]float32{
+	// A VRC6 pulse at full volume matches an APU pulse at full volume
+	VRC6:      0.608,
+	VRC7:      0.45,
+	FDS:       0.36,
+	MMC5:      0.42,
+	Namco163:  0.66,
+	Sunsoft5B: 0.67,
+}
+
+var expansionNames = map[ExpansionChip]string{
+	VRC6:      "VRC6",
+	VRC7:      "VRC7",
+	FDS:       "FDS",
+	MMC5:      "MMC5",
+	Namco163:  "Namco 163",
+	Sunsoft5B: "Sunsoft 5B",
+}
+
+func (chip ExpansionChip) String() string {
+	name, ok := expansionNames[chip]
+	if !ok {
+		return "unknown"
+	}
+	return name
+}
+
+// AudioExpansion is implemented by mappers containing an expansion sound chip,
+// whose output is mixed with the APU's.
+type AudioExpansion interface {
+	// ExpansionChip returns the type of the sound chip, which determines its
+	// mixing level.
+	ExpansionChip() ExpansionChip
+
+	// ClockAudio is called once per CPU cycle, alongside the APU.
+	ClockAudio()
+
+	// AudioOutput returns the chip's current output level, ranging from 0 to
+	// 1 of the chip's full scale.
+	AudioOutput() float32
+}
+
+// SetExpansion connects an expansion sound chip to the APU, mixing its output
+// with the APU's. Setting a nil expansion disconnects the current one.
+//
+// The expansion's output is muted along with the Expansion channel.
+func (a *APU) SetExpansion(exp AudioExpansion) {
+	a.exp = exp
+	if exp != nil {
+		a.expLevel = expansionLevels[exp.ExpansionChip()]
+	}
+}
+
+// expansionOutput returns the expansion chip's output, scaled to its level
+// relative to the APU.
+func (a *APU) expansionOutput() float32 {
+	if a.exp == nil || a.channels&Expansion == 0 {
+		return 0
+	}
+	return a.exp.AudioOutput() * a.expLevel
+}
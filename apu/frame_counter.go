@@ -0,0 +1,94 @@
+package apu
+
+// frameSteps holds the frame counter's step timings, in CPU cycles.
+type frameSteps struct {
+	step1Cycle       int
+	step2Cycle       int
+	step3Cycle       int
+	step4Cycle       int
+	fourStepFrameLen int
+	step5Cycle       int
+	fiveStepFrameLen int
+}
+
+var (
+	ntscFrameSteps = frameSteps{
+		step1Cycle:       7457,
+		step2Cycle:       14913,
+		step3Cycle:       22371,
+		step4Cycle:       29829,
+		fourStepFrameLen: 29830,
+		step5Cycle:       37281,
+		fiveStepFrameLen: 37282,
+	}
+	palFrameSteps = frameSteps{
+		step1Cycle:       8313,
+		step2Cycle:       16627,
+		step3Cycle:       24939,
+		step4Cycle:       33253,
+		fourStepFrameLen: 33254,
+		step5Cycle:       41565,
+		fiveStepFrameLen: 41566,
+	}
+)
+
+// frameCounter generates the quarter and half frame clocks driving the
+// channels' envelopes, sweeps and length counters, as well as the frame
+// interrupt.
+type frameCounter struct {
+	steps *frameSteps
+
+	fiveStep   bool
+	irqInhibit bool
+	irq        bool
+
+	cycles int
+}
+
+// write sets the frame counter's mode and interrupt inhibit flag, resetting
+// the sequence.
+func (f *frameCounter) write(d byte) {
+	f.fiveStep = d>>7 == 1
+	f.irqInhibit = d>>6&1 == 1
+
+	if f.irqInhibit {
+		f.irq = false
+	}
+
+	f.cycles = 0
+}
+
+// cycle advances the frame counter by a single CPU cycle, returning whether a
+// quarter and half frame clock should be generated.
+func (f *frameCounter) cycle() (quarter, half bool) {
+	f.cycles++
+
+	switch s := f.steps; f.cycles {
+	case s.step1Cycle, s.step3Cycle:
+		quarter = true
+	case s.step2Cycle:
+		quarter, half = true, true
+	case s.step4Cycle:
+		if !f.fiveStep {
+			quarter, half = true, true
+			f.setIRQ()
+		}
+	case s.fourStepFrameLen:
+		if !f.fiveStep {
+			f.setIRQ()
+			f.cycles = 0
+		}
+	case s.step5Cycle:
+		quarter, half = true, true
+	case s.fiveStepFrameLen:
+		f.cycles = 0
+	}
+
+	return quarter, half
+}
+
+func (f *frameCounter) setIRQ() {
+	if !f.irqInhibit {
+		f.irq = true
+	}
+}
@@ -0,0 +1,86 @@
+package apu
+
+import (
+	"math"
+)
+
+// Lookup tables of the NES's non-linear mixer, as described in
+// https://wiki.nesdev.com/w/index.php/APU_Mixer
+var (
+	// pulseTable is indexed by the sum of both pulse channels' output
+	pulseTable [31]float32
+	// tndTable is indexed by 3 * triangle + 2 * noise + dmc
+	tndTable [203]float32
+)
+
+func init() {
+	for n := 1; n < len(pulseTable); n++ {
+		pulseTable[n] = float32(95.52 / (8128.0/float64(n) + 100))
+	}
+	for n := 1; n < len(tndTable); n++ {
+		tndTable[n] = float32(163.67 / (24329.0/float64(n) + 100))
+	}
+}
+
+// mix mixes the channels' output levels into a single output level, ranging
+// from 0 to 1.
+func mix(pulse1, pulse2, triangle, noise, dmc byte) float32 {
+	return pulseTable[pulse1+pulse2] +
+		tndTable[3*int(triangle)+2*int(noise)+int(dmc)]
+}
+
+// filter is a first order low or high pass filter, running at the output
+// sample rate.
+type filter struct {
+	highPass bool
+	alpha    float32
+
+	prevIn  float32
+	prevOut float32
+}
+
+func newFilter(highPass bool, cutoff, sampleRate float64) *filter {
+	rc := 1 / (2 * math.Pi * cutoff)
+	dt := 1 / sampleRate
+
+	alpha := dt / (rc + dt)
+	if highPass {
+		alpha = rc / (rc + dt)
+	}
+
+	return &filter{
+		highPass: highPass,
+		alpha:    float32(alpha),
+	}
+}
+
+func (f *filter) apply(in float32) (out float32) {
+	if f.highPass {
+		out = f.alpha * (f.prevOut + in - f.prevIn)
+	} else {
+		out = f.prevOut + f.alpha*(in-f.prevOut)
+	}
+
+	f.prevIn = in
+	f.prevOut = out
+	return out
+}
+
+// filterChain emulates the NES's analog output stage, which consists of a 90Hz
+// and a 440Hz high pass filters followed by a 14kHz low pass filter.
+type filterChain []*filter
+
+func newFilterChain(sampleRate float64) filterChain {
+	return filterChain{
+		newFilter(true, 90, sampleRate),
+		newFilter(true, 440, sampleRate),
+		newFilter(false, 14000, sampleRate),
+	}
+}
+
+func (fc filterChain) apply(in float32) float32 {
+	for _, f := range fc {
+		in = f.apply(in)
+	}
+	return in
+}
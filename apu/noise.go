@@ -0,0 +1,83 @@
+package apu
+
+// Noise timer periods, in CPU cycles
+var (
+	noiseTable = [16]int{
+		4, 8, 16, 32, 64, 96, 128, 160, 202, 254, 380, 508, 762, 1016, 2034,
+		4068,
+	}
+	palNoiseTable = [16]int{
+		4, 8, 14, 30, 60, 88, 118, 148, 188, 236, 354, 472, 708, 944, 1890,
+		3778,
+	}
+)
+
+// noise implements the APU's pseudo-random noise channel.
+type noise struct {
+	env    envelope
+	length lengthCounter
+
+	// mode selects the short (93 step) sequence when set
+	mode  bool
+	shift uint16
+
+	// periods is the region's timer period table
+	periods *[16]int
+
+	period int
+	timer  int
+}
+
+func newNoise() *noise {
+	return &noise{
+		// The shift register is loaded with 1 on power up
+		shift:   1,
+		periods: &noiseTable,
+		period:  noiseTable[0],
+	}
+}
+
+// write sets one of the channel's 4 registers, specified by reg (0 ~ 3).
+func (n *noise) write(reg int, d byte) {
+	switch reg {
+	case 0:
+		n.length.halt = d>>5&1 == 1
+		n.env.write(d)
+	case 2:
+		n.mode = d>>7 == 1
+		n.period = n.periods[d&0xf]
+	case 3:
+		n.length.load(d >> 3)
+		n.env.start = true
+	}
+}
+
+// clockTimer is called every CPU cycle, clocking the shift register when the
+// timer expires.
+func (n *noise) clockTimer() {
+	if n.timer > 0 {
+		n.timer--
+		return
+	}
+
+	n.timer = n.period - 1
+
+	// Feedback is bit 0 xor either bit 6 or bit 1, depending on mode
+	tap := uint(1)
+	if n.mode {
+		tap = 6
+	}
+	feedback := n.shift&1 ^ n.shift>>tap&1
+
+	n.shift >>= 1
+	n.shift |= feedback << 14
+}
+
+// output returns the channel's current output level (0 ~ 15).
+func (n *noise) output() byte {
+	if n.length.value == 0 || n.shift&1 == 1 {
+		return 0
+	}
+
+	return n.env.volume()
+}
@@ -0,0 +1,127 @@
+package apu
+
+// negateMode describes the way a pulse channel's sweep unit negates the
+// period change. Pulse 1 uses ones' complement while pulse 2 uses two's
+// complement.
+type negateMode int
+
+const (
+	onesComplement negateMode = iota
+	twosComplement
+)
+
+var dutyTable = [4][8]byte{
+	{0, 1, 0, 0, 0, 0, 0, 0},
+	{0, 1, 1, 0, 0, 0, 0, 0},
+	{0, 1, 1, 1, 1, 0, 0, 0},
+	{1, 0, 0, 1, 1, 1, 1, 1},
+}
+
+// pulse implements one of the APU's two square wave channels.
+type pulse struct {
+	env    envelope
+	length lengthCounter
+
+	duty    byte
+	dutyPos byte
+
+	period int
+	timer  int
+
+	// Sweep unit
+	sweepEnabled bool
+	sweepPeriod  byte
+	sweepNegate  bool
+	sweepShift   byte
+	sweepDivider byte
+	sweepReload  bool
+	negateMode   negateMode
+}
+
+func newPulse(mode negateMode) *pulse {
+	return &pulse{negateMode: mode}
+}
+
+// write sets one of the channel's 4 registers, specified by reg (0 ~ 3).
+func (p *pulse) write(reg int, d byte) {
+	switch reg {
+	case 0:
+		p.duty = d >> 6
+		p.length.halt = d>>5&1 == 1
+		p.env.write(d)
+	case 1:
+		p.sweepEnabled = d>>7 == 1
+		p.sweepPeriod = d >> 4 & 7
+		p.sweepNegate = d>>3&1 == 1
+		p.sweepShift = d & 7
+		p.sweepReload = true
+	case 2:
+		p.period = p.period&0x700 | int(d)
+	case 3:
+		p.period = p.period&0xff | int(d&7)<<8
+		p.length.load(d >> 3)
+
+		// Restart the sequencer and envelope
+		p.dutyPos = 0
+		p.env.start = true
+	}
+}
+
+// clockTimer is called every APU cycle (every other CPU cycle), stepping the
+// sequencer when the timer expires.
+func (p *pulse) clockTimer() {
+	if p.timer > 0 {
+		p.timer--
+		return
+	}
+
+	p.timer = p.period
+	p.dutyPos = (p.dutyPos + 1) % 8
+}
+
+// clockSweep is called on every half frame, updating the channel's period.
+func (p *pulse) clockSweep() {
+	if p.sweepDivider == 0 && p.sweepEnabled && p.sweepShift > 0 &&
+		!p.sweepMuted() {
+
+		p.period = p.targetPeriod()
+	}
+
+	if p.sweepDivider == 0 || p.sweepReload {
+		p.sweepDivider = p.sweepPeriod
+		p.sweepReload = false
+	} else {
+		p.sweepDivider--
+	}
+}
+
+// targetPeriod calculates the period the sweep unit is going to set.
+func (p *pulse) targetPeriod() int {
+	change := p.period >> p.sweepShift
+
+	if !p.sweepNegate {
+		return p.period + change
+	}
+
+	if p.negateMode == onesComplement {
+		return p.period - change - 1
+	}
+	return p.period - change
+}
+
+// sweepMuted returns whether the sweep unit is muting the channel. This
+// happens regardless of the sweep unit being enabled.
+func (p *pulse) sweepMuted() bool {
+	return p.period < 8 || p.targetPeriod() > 0x7ff
+}
+
+// output returns the channel's current output level (0 ~ 15).
+func (p *pulse) output() byte {
+	if p.length.value == 0 || p.sweepMuted() ||
+		dutyTable[p.duty][p.dutyPos] == 0 {
+
+		return 0
+	}
+
+	return p.env.volume()
+}
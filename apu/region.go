@@ -0,0 +1,25 @@
+package apu
+
+import (
+	"github.com/m4ntis/bones/region"
+)
+
+// SetRegion sets the APU's clock rate and rate tables to a region's.
+//
+// The PAL APU's noise and DMC periods and frame counter steps are adjusted to
+// its slower clock. The Dendy's APU is timed like NTSC's.
+func (a *APU) SetRegion(r region.Region) {
+	a.clockRate = r.CPUClockRate()
+	if a.blip != nil {
+		a.setBlipRates()
+	}
+
+	a.noise.periods = &noiseTable
+	a.dmc.periods = &dmcTable
+	a.frameCounter.steps = &ntscFrameSteps
+	if r == region.PAL {
+		a.noise.periods = &palNoiseTable
+		a.dmc.periods = &palDMCTable
+		a.frameCounter.steps = &palFrameSteps
+	}
+}
@@ -0,0 +1,101 @@
+package apu
+
+// EnvelopeState is a snapshot of a pulse or noise channel's envelope.
+type EnvelopeState struct {
+	Constant bool
+	Loop     bool
+
+	// Period is both the constant volume and the decay rate
+	Period byte
+	Decay  byte
+}
+
+// ChannelState is a snapshot of a single channel's registers and internal
+// state, meant for drawing channel scopes and debugging music drivers.
+//
+// Fields that don't apply to a channel are left zeroed.
+type ChannelState struct {
+	Channel Channel
+
+	// Period is the channel's timer period. For the pulse and triangle
+	// channels it is the 11 bit register value, for noise and DMC it is the
+	// rate in CPU cycles.
+	Period int
+
+	// Volume is the envelope's volume for the pulse and noise channels, and the
+	// output level for the DMC.
+	Volume byte
+
+	Duty     byte
+	Envelope EnvelopeState
+
+	// Length is the length counter's value. For the DMC it holds the amount of
+	// sample bytes remaining.
+	Length int
+
+	// Output is the channel's current output value, before mixing.
+	Output byte
+}
+
+// ChannelState returns a snapshot of a single channel's state.
+//
+// ChannelState returns a zeroed ChannelState if ch isn't a single channel.
+func (a *APU) ChannelState(ch Channel) ChannelState {
+	s := ChannelState{Channel: ch}
+
+	switch ch {
+	case Pulse1:
+		a.pulse1.state(&s)
+	case Pulse2:
+		a.pulse2.state(&s)
+	case Triangle:
+		s.Period = a.triangle.period
+		s.Length = int(a.triangle.length.value)
+		s.Output = a.triangle.output()
+	case Noise:
+		s.Period = a.noise.period
+		s.Volume = a.noise.env.volume()
+		s.Envelope = a.noise.env.state()
+		s.Length = int(a.noise.length.value)
+		s.Output = a.noise.output()
+	case DMC:
+		s.Period = a.dmc.period
+		s.Volume = a.dmc.level
+		s.Length = a.dmc.bytesRemaining
+		s.Output = a.dmc.output()
+	default:
+		return ChannelState{}
+	}
+
+	return s
+}
+
+// ChannelStates returns a snapshot of all channels' states, ordered pulse1,
+// pulse2, triangle, noise and dmc.
+func (a *APU) ChannelStates() []ChannelState {
+	return []ChannelState{
+		a.ChannelState(Pulse1),
+		a.ChannelState(Pulse2),
+		a.ChannelState(Triangle),
+		a.ChannelState(Noise),
+		a.ChannelState(DMC),
+	}
+}
+
+func (p *pulse) state(s *ChannelState) {
+	s.Period = p.period
+	s.Volume = p.env.volume()
+	s.Duty = p.duty
+	s.Envelope = p.env.state()
+	s.Length = int(p.length.value)
+	s.Output = p.output()
+}
+
+func (e *envelope) state() EnvelopeState {
+	return EnvelopeState{
+		Constant: e.constant,
+		Loop:     e.loop,
+		Period:   e.period,
+		Decay:    e.decay,
+	}
+}
@@ -0,0 +1,72 @@
+package apu
+
+var triangleTable = [32]byte{
+	15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0,
+	0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+// triangle implements the APU's triangle wave channel.
+type triangle struct {
+	length lengthCounter
+
+	period int
+	timer  int
+	seqPos byte
+
+	// Linear counter
+	control       bool
+	linearPeriod  byte
+	linearCounter byte
+	linearReload  bool
+}
+
+// write sets one of the channel's 4 registers, specified by reg (0 ~ 3).
+func (t *triangle) write(reg int, d byte) {
+	switch reg {
+	case 0:
+		t.control = d>>7 == 1
+		t.length.halt = t.control
+		t.linearPeriod = d & 0x7f
+	case 2:
+		t.period = t.period&0x700 | int(d)
+	case 3:
+		t.period = t.period&0xff | int(d&7)<<8
+		t.length.load(d >> 3)
+		t.linearReload = true
+	}
+}
+
+// clockTimer is called every CPU cycle, stepping the sequencer when the timer
+// expires and both the length and linear counters are non zero.
+func (t *triangle) clockTimer() {
+	if t.timer > 0 {
+		t.timer--
+		return
+	}
+
+	t.timer = t.period
+	if t.length.value > 0 && t.linearCounter > 0 {
+		t.seqPos = (t.seqPos + 1) % 32
+	}
+}
+
+// clockLinearCounter is called on every quarter frame.
+func (t *triangle) clockLinearCounter() {
+	if t.linearReload {
+		t.linearCounter = t.linearPeriod
+	} else if t.linearCounter > 0 {
+		t.linearCounter--
+	}
+
+	if !t.control {
+		t.linearReload = false
+	}
+}
+
+// output returns the channel's current output level (0 ~ 15).
+//
+// Silencing the triangle channel stops its sequencer without resetting its
+// output, so the output is determined by the sequencer alone.
+func (t *triangle) output() byte {
+	return triangleTable[t.seqPos]
+}
@@ -0,0 +1,88 @@
+package apu
+
+var lengthTable = [32]byte{
+	10, 254, 20, 2, 40, 4, 80, 6, 160, 8, 60, 10, 14, 12, 26, 14,
+	12, 16, 24, 18, 48, 20, 96, 22, 192, 24, 72, 26, 16, 28, 32, 30,
+}
+
+// lengthCounter silences a channel after a set amount of half frames.
+type lengthCounter struct {
+	value   byte
+	halt    bool
+	enabled bool
+}
+
+// load sets the counter's value from the length table, as long as the channel
+// is enabled.
+func (l *lengthCounter) load(idx byte) {
+	if l.enabled {
+		l.value = lengthTable[idx]
+	}
+}
+
+// setEnabled enables or disables the counter. Disabling the counter
+// immediately silences the channel.
+func (l *lengthCounter) setEnabled(enabled bool) {
+	l.enabled = enabled
+	if !enabled {
+		l.value = 0
+	}
+}
+
+// clock is called on every half frame, decrementing the counter unless halted.
+func (l *lengthCounter) clock() {
+	if l.value > 0 && !l.halt {
+		l.value--
+	}
+}
+
+// envelope generates either a constant volume or a decaying saw envelope for
+// the pulse and noise channels.
+type envelope struct {
+	start    bool
+	loop     bool
+	constant bool
+
+	// period is both the constant volume and the divider's period
+	period  byte
+	divider byte
+	decay   byte
+}
+
+// write sets the envelope's parameters from the --LC VVVV bits shared by the
+// pulse and noise channels' first register.
+func (e *envelope) write(d byte) {
+	e.loop = d>>5&1 == 1
+	e.constant = d>>4&1 == 1
+	e.period = d & 0xf
+}
+
+// clock is called on every quarter frame.
+func (e *envelope) clock() {
+	if e.start {
+		e.start = false
+		e.decay = 15
+		e.divider = e.period
+		return
+	}
+
+	if e.divider > 0 {
+		e.divider--
+		return
+	}
+
+	e.divider = e.period
+	if e.decay > 0 {
+		e.decay--
+	} else if e.loop {
+		e.decay = 15
+	}
+}
+
+// volume returns the envelope's current output volume.
+func (e *envelope) volume() byte {
+	if e.constant {
+		return e.period
+	}
+	return e.decay
+}
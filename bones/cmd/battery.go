@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/m4ntis/bones/ines"
+)
+
+// batteryFlushInterval is how often battery backed memory is flushed to its
+// save file while running, so that progress isn't lost if BoNES is killed
+const batteryFlushInterval = 10 * time.Second
+
+// batterySave persists the battery backed memory of a ROM's mapper to a .sav
+// file.
+type batterySave struct {
+	path   string
+	mapper ines.BatteryMapper
+
+	// saved is the memory's contents as last written to the file, to skip
+	// flushing when nothing changed
+	saved []byte
+
+	// mu serializes flushes from the periodic flushing and on exit
+	mu sync.Mutex
+
+	// runOnNES runs a function where it can read the memory without racing
+	// with the running game, which is bones.NES.Sync once the NES is created
+	runOnNES func(f func())
+}
+
+// newBatterySave returns the batterySave of a ROM in saveDir, or in the ROM's
+// directory if saveDir is empty. ok is false if the ROM has no battery backed
+// memory.
+func newBatterySave(rom *ines.ROM, romPath string,
+	saveDir string) (s *batterySave, ok bool) {
+
+	mapper, ok := rom.Mapper.(ines.BatteryMapper)
+	if !ok || rom.Header.PersistentMemory == 0 {
+		return nil, false
+	}
+
+	if saveDir == "" {
+		saveDir = filepath.Dir(romPath)
+	}
+	name := filepath.Base(romPath)
+	name = strings.TrimSuffix(name, filepath.Ext(name)) + ".sav"
+
+	return &batterySave{
+		path:   filepath.Join(saveDir, name),
+		mapper: mapper,
+
+		runOnNES: func(f func()) { f() },
+	}, true
+}
+
+// load restores the memory from the save file, if there is one.
+func (s *batterySave) load() error {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	s.mapper.LoadBattery(data)
+	s.saved = s.mapper.Battery()
+	return nil
+}
+
+// flush writes the memory to the save file if it changed since the last
+// flush.
+//
+// The file is written to a temporary file first and then renamed, so that a
+// save is never left half written.
+func (s *batterySave) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var data []byte
+	s.runOnNES(func() {
+		data = s.mapper.Battery()
+	})
+	if s.saved != nil && bytes.Equal(data, s.saved) {
+		return nil
+	}
+
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+
+	s.saved = data
+	return nil
+}
+
+// flushPeriodically flushes the memory every batteryFlushInterval until stopc
+// is closed, printing failed flushes.
+//
+// The memory is copied between instructions while the NES is running, so a
+// flush may catch a game in the middle of saving. The next flush, or the one
+// on exit, corrects it.
+func (s *batterySave) flushPeriodically(stopc <-chan struct{}) {
+	ticker := time.NewTicker(batteryFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.printFlush()
+		case <-stopc:
+			return
+		}
+	}
+}
+
+// printFlush flushes the memory, printing the error if it failed.
+func (s *batterySave) printFlush() {
+	if err := s.flush(); err != nil {
+		fmt.Printf("Error writing save file %s:\n%s\n", s.path, err)
+	}
+}
@@ -10,6 +10,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
+const (
+	sampleRate = 44100
+)
+
 var (
 	// benchCmd represents the run command
 	benchCmd = &cobra.Command{
@@ -20,9 +24,10 @@ var (
 
 			ctrl := new(io.Controller)
 			disp := io.NewBenchDisplay()
+			spk := io.NewBenchSpeaker(sampleRate)
 
-			n := bones.New(disp, ctrl, bones.ModeRun)
-			n.Load(rom)
+			n := bones.New(disp, spk, ctrl, bones.ModeRun)
+			loadRom(n, rom)
 
 			go n.Start()
 
@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/m4ntis/bones"
+	"github.com/m4ntis/bones/apu"
 	"github.com/m4ntis/bones/ines"
+	"github.com/m4ntis/bones/io"
+	"github.com/m4ntis/bones/region"
 )
 
 func openRom(cmdName string, args []string) *ines.ROM {
@@ -13,6 +17,10 @@ func openRom(cmdName string, args []string) *ines.ROM {
 		os.Exit(1)
 	}
 
+	if dbPath != "" {
+		loadDatabase(dbPath)
+	}
+
 	filename := args[0]
 	f, err := os.Open(filename)
 	if err != nil {
@@ -28,3 +36,60 @@ func openRom(cmdName string, args []string) *ines.ROM {
 
 	return rom
 }
+
+// romRegion returns the region set by the --region flag, or the one in the
+// rom's header if the flag isn't set.
+func romRegion(rom *ines.ROM) region.Region {
+	if regionName == "" {
+		return rom.Header.Region()
+	}
+
+	r, err := region.Parse(regionName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	return r
+}
+
+// loadRom loads a rom into the NES, running it in the region returned by
+// romRegion.
+func loadRom(n *bones.NES, rom *ines.ROM) {
+	n.Load(rom)
+	n.SetRegion(romRegion(rom))
+}
+
+// loadDatabase adds the games in the database file at path to the game
+// database roms are looked up in.
+func loadDatabase(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Error opening file %s:\n%s\n", path, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	err = ines.LoadDatabase(f)
+	if err != nil {
+		fmt.Printf("Error reading game database %s:\n%s\n", path, err)
+		os.Exit(1)
+	}
+}
+
+// openSpeaker opens the PulseAudio speaker set by the audio flags, or returns
+// a nil speaker if audio is disabled. closeSpk closes the speaker if one was
+// opened.
+func openSpeaker() (spk apu.Speaker, closeSpk func()) {
+	if noAudio {
+		return nil, func() {}
+	}
+
+	pulseSpk, err := io.NewPulseSpeaker(audioDevice, sampleRate, audioLatency)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	return pulseSpk, func() { pulseSpk.Close() }
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"time"
+
 	"github.com/m4ntis/bones"
 	"github.com/m4ntis/bones/bones/cmd/dbg"
 	"github.com/m4ntis/bones/io"
@@ -17,7 +19,8 @@ var (
 The command prompts up an interactive gdb style debugger, waiting for user
 input before executing each command. It has pretty basic functionality including
 breakpoints, next and continue instructions, as well as printing registers, ram
-and vram values.
+and vram values. The APU's channels can be muted and soloed while listening to
+the game's audio, which is played as in the run command.
 
 The debugger also opens a separate window for displaying the ppu's output. It
 may appear as frozen or 'Not responding' or anything of the sort, but that is
@@ -33,8 +36,11 @@ debugger.
 			ctrl := new(io.Controller)
 			disp := io.NewDisplay(ctrl, displayFPS, scale)
 
-			n := bones.New(disp, ctrl, bones.ModeDebug)
-			n.Load(rom)
+			spk, closeSpk := openSpeaker()
+			defer closeSpk()
+
+			n := bones.New(disp, spk, ctrl, bones.ModeDebug)
+			loadRom(n, rom)
 			d := dbg.New(n)
 
 			go n.Start()
@@ -54,6 +60,12 @@ func init() {
 		false, "Display small FPS counter")
 	flags.Float64VarP(&scale, "scale", "s", 4.0,
 		"Set display scaling (240x256 * scale)")
+	flags.BoolVar(&noAudio, "no-audio", false,
+		"Disable audio output")
+	flags.StringVar(&audioDevice, "audio-device", "",
+		"PulseAudio sink to play audio to, defaults to the default sink")
+	flags.DurationVar(&audioLatency, "audio-latency", 50*time.Millisecond,
+		"Amount of audio buffered ahead of playback")
 
 	// Make bones dbg's usage be 'bones dbg <romname>.nes'
 	dbgCmd.SetUsageTemplate(`Usage:
@@ -166,6 +166,65 @@ func commands(dbg *Debugger) []swerve.Command {
 
 			Desc: "Prints the cpu's registers' status",
 		},
+		swerve.Command{
+			Name:    "mute",
+			Aliases: []string{},
+
+			Run: func(p swerve.Prompt, args []string) {
+				dbg.n.Mute(parseChannels(args))
+			},
+			ValidateArgs: argsChannelValidator,
+
+			Desc:  "Mute APU channels",
+			Usage: "mute <channel>...",
+			Help:  "Mutes the specified APU channels, out of pulse1, pulse2, triangle, noise, dmc and expansion",
+		},
+		swerve.Command{
+			Name:    "unmute",
+			Aliases: []string{},
+
+			Run: func(p swerve.Prompt, args []string) {
+				dbg.n.Unmute(parseChannels(args))
+			},
+			ValidateArgs: argsChannelValidator,
+
+			Desc:  "Unmute APU channels",
+			Usage: "unmute <channel>...",
+			Help:  "Unmutes the specified APU channels, out of pulse1, pulse2, triangle, noise, dmc and expansion",
+		},
+		swerve.Command{
+			Name:    "solo",
+			Aliases: []string{},
+
+			Run: func(p swerve.Prompt, args []string) {
+				dbg.n.Solo(parseChannels(args))
+			},
+			ValidateArgs: argsChannelValidator,
+
+			Desc:  "Solo APU channels",
+			Usage: "solo <channel>...",
+			Help:  "Mutes all APU channels except for the specified ones, out of pulse1, pulse2, triangle, noise, dmc and expansion",
+		},
+		swerve.Command{
+			Name:    "channels",
+			Aliases: []string{"ch"},
+
+			Run: func(p swerve.Prompt, args []string) {
+				mixed := dbg.n.APU().Channels()
+
+				for _, s := range dbg.n.ChannelStates() {
+					muted := ""
+					if mixed&s.Channel == 0 {
+						muted = " (muted)"
+					}
+
+					p.Printf("%s%s: %s\n", s.Channel, muted,
+						strings.Trim(fmt.Sprintf("%+v", s), "{}"))
+				}
+			},
+
+			Desc: "Prints the APU channels' status",
+		},
 		swerve.Command{
 			Name:    "list",
 			Aliases: []string{"ls"},
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/m4ntis/bones"
+	"github.com/m4ntis/bones/apu"
 	"github.com/m4ntis/bones/cpu"
 	"github.com/m4ntis/swerve"
 )
@@ -143,6 +145,35 @@ func argsLenValidator(lens []int) func(swerve.Prompt, []string) bool {
 	}
 }
 
+// argsChannelValidator validates one or more APU channel name arguments.
+func argsChannelValidator(p swerve.Prompt, args []string) (ok bool) {
+	if len(args) == 0 {
+		p.Println("Error: This command takes at least 1 argument")
+		return false
+	}
+
+	for _, name := range args {
+		_, err := apu.ParseChannel(strings.ToLower(name))
+		if err != nil {
+			p.Printf("Error: %s\n", err)
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseChannels parses a list of valid channel names into a set of APU
+// channels.
+func parseChannels(names []string) (channels apu.Channel) {
+	for _, name := range names {
+		ch, _ := apu.ParseChannel(strings.ToLower(name))
+		channels |= ch
+	}
+
+	return channels
+}
+
 func list(b bones.Break) {
 	for i, inst := range b.Code {
 		if i == b.PCIdx {
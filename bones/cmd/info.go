@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+
+	"github.com/m4ntis/bones/cpu"
+	"github.com/m4ntis/bones/ines"
+	"github.com/spf13/cobra"
+)
+
+var (
+	infoJSON bool
+)
+
+var timingNames = map[int]string{
+	ines.TimingNTSC:        "NTSC",
+	ines.TimingPAL:         "PAL",
+	ines.TimingMultiRegion: "Multi-region",
+	ines.TimingDendy:       "Dendy",
+}
+
+var consoleNames = map[int]string{
+	ines.ConsoleNES:            "NES/Famicom",
+	ines.ConsoleVsSystem:       "Vs. System",
+	ines.ConsolePlaychoice10:   "Playchoice-10",
+	ines.ConsoleFamiclone:      "Famiclone with decimal mode",
+	ines.ConsoleEPSM:           "NES/Famicom with EPSM",
+	ines.ConsoleVT01:           "VT01",
+	ines.ConsoleVT02:           "VT02",
+	ines.ConsoleVT03:           "VT03",
+	ines.ConsoleVT09:           "VT09",
+	ines.ConsoleVT32:           "VT32",
+	ines.ConsoleVT369:          "VT369",
+	ines.ConsoleUM6578:         "UM6578",
+	ines.ConsoleFamicomNetwork: "Famicom Network System",
+}
+
+// romInfo is the report the info command prints of a rom.
+type romInfo struct {
+	File  string `json:"file"`
+	Error string `json:"error,omitempty"`
+
+	Format          string `json:"format,omitempty"`
+	Mapper          int    `json:"mapper"`
+	Submapper       int    `json:"submapper"`
+	MapperSupported bool   `json:"mapperSupported"`
+
+	InDatabase bool   `json:"inDatabase"`
+	Title      string `json:"title,omitempty"`
+	Board      string `json:"board,omitempty"`
+
+	PrgROM  *sectionInfo `json:"prgRom,omitempty"`
+	ChrROM  *sectionInfo `json:"chrRom,omitempty"`
+	Trainer *sectionInfo `json:"trainer,omitempty"`
+
+	PrgRAMSize   int `json:"prgRamSize"`
+	PrgNVRAMSize int `json:"prgNvramSize"`
+	ChrRAMSize   int `json:"chrRamSize"`
+	ChrNVRAMSize int `json:"chrNvramSize"`
+
+	Mirroring       string `json:"mirroring,omitempty"`
+	Battery         bool   `json:"battery"`
+	Timing          string `json:"timing,omitempty"`
+	ConsoleType     string `json:"consoleType,omitempty"`
+	VsPPUType       *int   `json:"vsPpuType,omitempty"`
+	VsHardwareType  *int   `json:"vsHardwareType,omitempty"`
+	MiscROMs        int    `json:"miscRoms"`
+	ExpansionDevice int    `json:"expansionDevice"`
+
+	// Vectors are only read for roms whose mapper is supported
+	Vectors *vectorsInfo `json:"vectors,omitempty"`
+}
+
+// sectionInfo describes a section of a rom's data.
+type sectionInfo struct {
+	Size  int    `json:"size"`
+	CRC32 string `json:"crc32"`
+	SHA1  string `json:"sha1"`
+}
+
+type vectorsInfo struct {
+	NMI   int `json:"nmi"`
+	Reset int `json:"reset"`
+	IRQ   int `json:"irq"`
+}
+
+var (
+	// infoCmd represents the info command
+	infoCmd = &cobra.Command{
+		Use:   "info",
+		Short: "Print a report of iNES roms",
+		Long: `The info command parses NES roms, in iNES or NES 2.0 format, and prints
+their header fields, the sizes and hashes of their PRG ROM, CHR ROM and trainer,
+their interrupt vectors and whether their mapper is supported.
+
+Roms found in the game database are reported with their corrected header, and
+the game's title and board.
+
+Multiple roms can be given, and reported as a JSON array with --json, to audit
+rom collections.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println("Usage:\n  bones info <romname>.nes...")
+				os.Exit(1)
+			}
+
+			if dbPath != "" {
+				loadDatabase(dbPath)
+			}
+
+			infos := make([]*romInfo, len(args))
+			failed := false
+			for i, filename := range args {
+				infos[i] = readROMInfo(filename)
+				if infos[i].Error != "" {
+					failed = true
+				}
+			}
+
+			if infoJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(infos); err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+			} else {
+				for i, info := range infos {
+					if i > 0 {
+						fmt.Println()
+					}
+					printROMInfo(info)
+				}
+			}
+
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+// readROMInfo reads the report of the rom at filename. Errors reading the rom
+// are reported in the info's Error field.
+func readROMInfo(filename string) *romInfo {
+	info := &romInfo{File: filename}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	defer f.Close()
+
+	dump, err := ines.ReadDump(f)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	h := dump.Header
+
+	info.Format = "iNES"
+	if h.NES20 {
+		info.Format = "NES 2.0"
+	}
+	info.Mapper = h.MapperNumber
+	info.Submapper = h.Submapper
+	info.MapperSupported = ines.MapperSupported(h.MapperNumber)
+
+	info.InDatabase = dump.InDatabase
+	info.Title = dump.Title
+	info.Board = dump.Board
+
+	info.PrgROM = newSectionInfo(dump.PrgROM)
+	info.ChrROM = newSectionInfo(dump.ChrROM)
+	if dump.Trainer != nil {
+		info.Trainer = newSectionInfo(dump.Trainer)
+	}
+
+	info.PrgRAMSize = h.PrgRAMSize
+	info.PrgNVRAMSize = h.PrgNVRAMSize
+	info.ChrRAMSize = h.ChrRAMSize
+	info.ChrNVRAMSize = h.ChrNVRAMSize
+
+	switch {
+	case h.IgnoreMirror == 1:
+		info.Mirroring = "Four screen"
+	case h.Mirroring == ines.VerticalMirroring:
+		info.Mirroring = "Vertical"
+	default:
+		info.Mirroring = "Horizontal"
+	}
+	info.Battery = h.PersistentMemory == 1
+	info.Timing = timingNames[h.Timing]
+	info.ConsoleType = consoleNames[h.ConsoleType]
+	if h.ConsoleType == ines.ConsoleVsSystem {
+		info.VsPPUType = &h.VsPPUType
+		info.VsHardwareType = &h.VsHardwareType
+	}
+	info.MiscROMs = h.MiscROMs
+	info.ExpansionDevice = h.ExpansionDevice
+
+	if info.MapperSupported {
+		rom, err := dump.ROM()
+		if err != nil {
+			info.Error = err.Error()
+			return info
+		}
+
+		c := cpu.New(nil, nil, nil)
+		c.Load(rom)
+		vec := c.Vectors()
+		info.Vectors = &vectorsInfo{NMI: vec[0], Reset: vec[1], IRQ: vec[2]}
+	}
+
+	return info
+}
+
+func newSectionInfo(data []byte) *sectionInfo {
+	return &sectionInfo{
+		Size:  len(data),
+		CRC32: fmt.Sprintf("%08x", crc32.ChecksumIEEE(data)),
+		SHA1:  fmt.Sprintf("%x", sha1.Sum(data)),
+	}
+}
+
+// printROMInfo prints a rom's report in a human readable form.
+func printROMInfo(info *romInfo) {
+	fmt.Printf("%s:\n", info.File)
+	if info.Format == "" {
+		fmt.Printf("  Error: %s\n", info.Error)
+		return
+	}
+
+	supported := "supported"
+	if !info.MapperSupported {
+		supported = "not supported"
+	}
+
+	fmt.Printf("  Format:           %s\n", info.Format)
+	fmt.Printf("  Mapper:           %d, submapper %d (%s)\n", info.Mapper,
+		info.Submapper, supported)
+	if info.InDatabase {
+		fmt.Printf("  Database:         %s\n", info.Title)
+		fmt.Printf("  Board:            %s\n", info.Board)
+	} else {
+		fmt.Printf("  Database:         no match\n")
+	}
+
+	printSection("PRG ROM", info.PrgROM)
+	printSection("CHR ROM", info.ChrROM)
+	if info.Trainer != nil {
+		printSection("Trainer", info.Trainer)
+	} else {
+		fmt.Printf("  Trainer:          none\n")
+	}
+
+	fmt.Printf("  PRG RAM:          %d bytes, %d bytes non volatile\n",
+		info.PrgRAMSize, info.PrgNVRAMSize)
+	fmt.Printf("  CHR RAM:          %d bytes, %d bytes non volatile\n",
+		info.ChrRAMSize, info.ChrNVRAMSize)
+	fmt.Printf("  Mirroring:        %s\n", info.Mirroring)
+	fmt.Printf("  Battery:          %t\n", info.Battery)
+	fmt.Printf("  Timing:           %s\n", info.Timing)
+	fmt.Printf("  Console:          %s\n", info.ConsoleType)
+	if info.VsPPUType != nil {
+		fmt.Printf("  Vs. PPU:          %d\n", *info.VsPPUType)
+		fmt.Printf("  Vs. hardware:     %d\n", *info.VsHardwareType)
+	}
+	fmt.Printf("  Misc ROMs:        %d\n", info.MiscROMs)
+	fmt.Printf("  Expansion device: %d\n", info.ExpansionDevice)
+
+	if info.Vectors != nil {
+		fmt.Printf("  Vectors:          NMI $%04x, Reset $%04x, IRQ $%04x\n",
+			info.Vectors.NMI, info.Vectors.Reset, info.Vectors.IRQ)
+	}
+	if info.Error != "" {
+		fmt.Printf("  Error: %s\n", info.Error)
+	}
+}
+
+func printSection(name string, s *sectionInfo) {
+	fmt.Printf("  %-17s %d bytes, CRC32 %s, SHA-1 %s\n", name+":", s.Size,
+		s.CRC32, s.SHA1)
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+
+	infoCmd.Flags().BoolVar(&infoJSON, "json", false,
+		"Print the report as a JSON array")
+
+	// Make bones info's usage be 'bones info <romname>.nes...'
+	infoCmd.SetUsageTemplate(`Usage:
+  bones info <romname>.nes...{{if gt (len .Aliases) 0}}
+
+Aliases:
+  {{.NameAndAliases}}{{end}}{{if .HasExample}}
+
+Examples:
+{{.Example}}{{end}}{{if .HasAvailableSubCommands}}
+
+Available Commands:{{range .Commands}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
+
+Flags:
+{{.LocalFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
+
+Global Flags:
+{{.InheritedFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasHelpSubCommands}}
+
+Additional help topics:{{range .Commands}}{{if .IsAdditionalHelpTopicCommand}}
+  {{rpad .CommandPath .CommandPathPadding}} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableSubCommands}}
+
+Use "{{.CommandPath}} [command] --help" for more information about a command.{{end}}
+`)
+}
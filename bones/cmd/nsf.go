@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/m4ntis/bones/io"
+	"github.com/m4ntis/bones/nsf"
+	"github.com/spf13/cobra"
+)
+
+var (
+	nsfTrack      int
+	nsfOut        string
+	nsfSeconds    float64
+	nsfSampleRate int
+)
+
+var (
+	// nsfCmd represents the nsf command
+	nsfCmd = &cobra.Command{
+		Use:   "nsf",
+		Short: "Render an NSF track to a wav file",
+		Long: `The nsf command plays a track of an NSF (NES Sound Format) file without
+a display and records the APU's output to a wav file.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			n := openNSF(cmd.Use, args)
+
+			track := nsfTrack
+			if track == 0 {
+				track = n.Header.StartingSong
+			}
+			if track < 1 || track > n.Header.TotalSongs {
+				fmt.Printf("Invalid track %d, NSF contains %d tracks\n", track,
+					n.Header.TotalSongs)
+				os.Exit(1)
+			}
+
+			f, err := os.Create(nsfOut)
+			if err != nil {
+				fmt.Printf("Error creating file %s:\n%s\n", nsfOut, err)
+				os.Exit(1)
+			}
+			defer f.Close()
+
+			spk, err := io.NewWAVSpeaker(f, nsfSampleRate,
+				int(nsfSeconds*float64(nsfSampleRate)))
+			if err != nil {
+				fmt.Printf("Error writing to file %s:\n%s\n", nsfOut, err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Rendering track %d/%d: %s - %s\n", track,
+				n.Header.TotalSongs, n.Header.SongName, n.Header.Artist)
+
+			p := nsf.NewPlayer(n, spk)
+			err = p.Init(track)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+		loop:
+			for {
+				select {
+				case <-spk.Done():
+					break loop
+				default:
+					err = p.Step()
+					if err != nil {
+						fmt.Println(err)
+						os.Exit(1)
+					}
+				}
+			}
+
+			err = spk.Close()
+			if err != nil {
+				fmt.Printf("Error writing to file %s:\n%s\n", nsfOut, err)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func openNSF(cmdName string, args []string) *nsf.NSF {
+	if len(args) != 1 {
+		fmt.Printf("Usage:\n  bones %s <filename>.nsf\n", cmdName)
+		os.Exit(1)
+	}
+
+	filename := args[0]
+	f, err := os.Open(filename)
+	if err != nil {
+		fmt.Printf("Error opening file %s:\n%s\n", filename, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	n, err := nsf.Parse(f)
+	if err != nil {
+		fmt.Printf("Error parsing NSF file %s:\n%s\n", filename, err)
+		os.Exit(1)
+	}
+
+	return n
+}
+
+func init() {
+	rootCmd.AddCommand(nsfCmd)
+
+	flags := nsfCmd.Flags()
+
+	flags.IntVarP(&nsfTrack, "track", "t", 0,
+		"Track to render, defaults to the NSF's starting track")
+	flags.StringVarP(&nsfOut, "out", "o", "track.wav",
+		"Output wav file")
+	flags.Float64Var(&nsfSeconds, "seconds", 60,
+		"Recording length in seconds")
+	flags.IntVar(&nsfSampleRate, "sample-rate", sampleRate,
+		"Output sample rate in Hz")
+
+	// Make bones nsf's usage be 'bones nsf <filename>.nsf'
+	nsfCmd.SetUsageTemplate(`Usage:
+  bones nsf <filename>.nsf{{if gt (len .Aliases) 0}}
+
+Aliases:
+  {{.NameAndAliases}}{{end}}{{if .HasExample}}
+
+Examples:
+{{.Example}}{{end}}{{if .HasAvailableSubCommands}}
+
+Available Commands:{{range .Commands}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
+
+Flags:
+{{.LocalFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
+
+Global Flags:
+{{.InheritedFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasHelpSubCommands}}
+
+Additional help topics:{{range .Commands}}{{if .IsAdditionalHelpTopicCommand}}
+  {{rpad .CommandPath .CommandPathPadding}} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableSubCommands}}
+
+Use "{{.CommandPath}} [command] --help" for more information about a command.{{end}}
+`)
+}
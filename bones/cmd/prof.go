@@ -23,8 +23,8 @@ var (
 			ctrl := new(io.Controller)
 			disp := io.NewDisplay(ctrl, displayFPS, scale)
 
-			n := bones.New(disp, ctrl, bones.ModeRun)
-			n.Load(rom)
+			n := bones.New(disp, nil, ctrl, bones.ModeRun)
+			loadRom(n, rom)
 
 			go n.Start()
 			disp.Run()
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/m4ntis/bones"
+	"github.com/m4ntis/bones/apu"
+	"github.com/m4ntis/bones/io"
+	"github.com/m4ntis/bones/region"
+	"github.com/spf13/cobra"
+)
+
+var (
+	recordOut        string
+	recordSeconds    float64
+	recordFrames     int
+	recordChannels   []string
+	recordSampleRate int
+)
+
+var (
+	// recordAudioCmd represents the record-audio command
+	recordAudioCmd = &cobra.Command{
+		Use:   "record-audio",
+		Short: "Record an iNES program's audio to a wav file",
+		Long: `The record-audio command runs NES roms, in iNES format, without a
+display and records the APU's output to a wav file.
+
+The recording length is set either in seconds or in frames. Channels can be
+isolated by specifying which channels to record, out of pulse1, pulse2,
+triangle, noise, dmc and expansion.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			rom := openRom(cmd.Use, args)
+			r := romRegion(rom)
+
+			channels, err := parseChannels(recordChannels)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			f, err := os.Create(recordOut)
+			if err != nil {
+				fmt.Printf("Error creating file %s:\n%s\n", recordOut, err)
+				os.Exit(1)
+			}
+			defer f.Close()
+
+			spk, err := io.NewWAVSpeaker(f, recordSampleRate, recordLength(r))
+			if err != nil {
+				fmt.Printf("Error writing to file %s:\n%s\n", recordOut, err)
+				os.Exit(1)
+			}
+
+			ctrl := new(io.Controller)
+			disp := io.NewNullDisplay()
+
+			n := bones.New(disp, spk, ctrl, bones.ModeRun)
+			loadRom(n, rom)
+			n.APU().SetChannels(channels)
+
+			go n.Start()
+			<-spk.Done()
+			n.Stop()
+
+			err = spk.Close()
+			if err != nil {
+				fmt.Printf("Error writing to file %s:\n%s\n", recordOut, err)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+// recordLength returns the amount of samples to record, calculated from either
+// the frame count, at the region's frame rate, or the seconds flag.
+func recordLength(r region.Region) int {
+	if recordFrames > 0 {
+		return int(float64(recordFrames) / r.FrameRate() *
+			float64(recordSampleRate))
+	}
+
+	return int(recordSeconds * float64(recordSampleRate))
+}
+
+// parseChannels parses a list of channel names into a set of APU channels.
+func parseChannels(names []string) (channels apu.Channel, err error) {
+	for _, name := range names {
+		ch, err := apu.ParseChannel(strings.ToLower(name))
+		if err != nil {
+			return 0, err
+		}
+
+		channels |= ch
+	}
+
+	return channels, nil
+}
+
+func init() {
+	rootCmd.AddCommand(recordAudioCmd)
+
+	flags := recordAudioCmd.Flags()
+
+	flags.StringVarP(&recordOut, "out", "o", "out.wav",
+		"Output wav file")
+	flags.Float64Var(&recordSeconds, "seconds", 10,
+		"Recording length in seconds")
+	flags.IntVar(&recordFrames, "frames", 0,
+		"Recording length in frames, overrides --seconds")
+	flags.StringSliceVar(&recordChannels, "channels",
+		[]string{"pulse1", "pulse2", "triangle", "noise", "dmc", "expansion"},
+		"Channels to record")
+	flags.IntVar(&recordSampleRate, "sample-rate", sampleRate,
+		"Output sample rate in Hz")
+
+	// Make bones record-audio's usage be 'bones record-audio <romname>.nes'
+	recordAudioCmd.SetUsageTemplate(`Usage:
+  bones record-audio <romname>.nes{{if gt (len .Aliases) 0}}
+
+Aliases:
+  {{.NameAndAliases}}{{end}}{{if .HasExample}}
+
+Examples:
+{{.Example}}{{end}}{{if .HasAvailableSubCommands}}
+
+Available Commands:{{range .Commands}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
+
+Flags:
+{{.LocalFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
+
+Global Flags:
+{{.InheritedFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasHelpSubCommands}}
+
+Additional help topics:{{range .Commands}}{{if .IsAdditionalHelpTopicCommand}}
+  {{rpad .CommandPath .CommandPathPadding}} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableSubCommands}}
+
+Use "{{.CommandPath}} [command] --help" for more information about a command.{{end}}
+`)
+}
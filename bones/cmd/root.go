@@ -7,6 +7,11 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	regionName string
+	dbPath     string
+)
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "bones",
@@ -23,4 +28,8 @@ func Execute() {
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&regionName, "region", "",
+		"Console region to emulate, ntsc, pal or dendy (default from the rom's header)")
+	rootCmd.PersistentFlags().StringVar(&dbPath, "db", "",
+		"NES 2.0 XML game database to correct rom headers with, in addition to the built in one")
 }
@@ -1,6 +1,12 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/m4ntis/bones"
 	"github.com/m4ntis/bones/io"
 	"github.com/spf13/cobra"
@@ -9,6 +15,12 @@ import (
 var (
 	displayFPS bool
 	scale      float64
+
+	noAudio      bool
+	audioDevice  string
+	audioLatency time.Duration
+
+	saveDir string
 )
 
 var (
@@ -16,18 +28,61 @@ var (
 	runCmd = &cobra.Command{
 		Use:   "run",
 		Short: "Run an iNES program",
-		Long:  "The run command is used to run NES roms, in iNES format.\n",
+		Long: `The run command is used to run NES roms, in iNES format.
+
+Games with battery backed memory are saved to a .sav file named after the rom,
+which is loaded on start and written on exit and periodically while running.
+`,
 		Run: func(cmd *cobra.Command, args []string) {
 			rom := openRom(cmd.Use, args)
 
 			ctrl := new(io.Controller)
 			disp := io.NewDisplay(ctrl, displayFPS, scale)
 
-			n := bones.New(disp, ctrl, bones.ModeRun)
-			n.Load(rom)
+			spk, closeSpk := openSpeaker()
+			defer closeSpk()
+
+			// The save is loaded before the rom, whose trainer is loaded over
+			// the saved PRG RAM
+			save, ok := newBatterySave(rom, args[0], saveDir)
+			if ok {
+				if err := save.load(); err != nil {
+					fmt.Printf("Error reading save file %s:\n%s\n", save.path,
+						err)
+					os.Exit(1)
+				}
+			}
+
+			n := bones.New(disp, spk, ctrl, bones.ModeRun)
+			loadRom(n, rom)
+
+			if ok {
+				save.runOnNES = n.Sync
+
+				stopc := make(chan struct{})
+				defer close(stopc)
+				go save.flushPeriodically(stopc)
+
+				// Flush when interrupted, which exits without the display
+				// returning or the deferred calls running
+				c := make(chan os.Signal, 1)
+				signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+				go func() {
+					<-c
+					n.Stop()
+					save.printFlush()
+					closeSpk()
+					os.Exit(1)
+				}()
+			}
 
 			go n.Start()
 			disp.Run()
+
+			if ok {
+				n.Stop()
+				save.printFlush()
+			}
 		},
 	}
 )
@@ -41,6 +96,14 @@ func init() {
 		false, "Display small FPS counter")
 	flags.Float64VarP(&scale, "scale", "s", 4.0,
 		"Set display scaling (240x256 * scale)")
+	flags.BoolVar(&noAudio, "no-audio", false,
+		"Disable audio output")
+	flags.StringVar(&audioDevice, "audio-device", "",
+		"PulseAudio sink to play audio to, defaults to the default sink")
+	flags.DurationVar(&audioLatency, "audio-latency", 50*time.Millisecond,
+		"Amount of audio buffered ahead of playback")
+	flags.StringVar(&saveDir, "save-dir", "",
+		"Directory of .sav files, defaults to the rom's directory")
 
 	// Make bones run's usage be 'bones run <romname>.nes'
 	runCmd.SetUsageTemplate(`Usage:
@@ -2,6 +2,7 @@
 package cpu
 
 import (
+	"github.com/m4ntis/bones/apu"
 	"github.com/m4ntis/bones/ines"
 	"github.com/m4ntis/bones/io"
 	"github.com/m4ntis/bones/ppu"
@@ -24,7 +25,8 @@ type CPU struct {
 	RAM *RAM
 	Reg *Registers
 
-	irq   bool
+	// irq holds the sources currently asserting the IRQ line
+	irq   IRQSource
 	nmi   bool
 	reset bool
 
@@ -36,20 +38,20 @@ type CPU struct {
 // ram is passed to the CPU instead of initialized within, as it is shared with
 // other components via memory mapped i/o. It is the caller's responsibility to
 // initialize and pass it to the other parts of the NES.
-func New(p *ppu.PPU, ctrl *io.Controller) *CPU {
+func New(p *ppu.PPU, a *apu.APU, ctrl *io.Controller) *CPU {
 	ram := &RAM{}
 
 	c := &CPU{
 		RAM: ram,
 		Reg: &Registers{},
 
-		irq:   false,
 		nmi:   false,
 		reset: false,
 	}
 
 	ram.CPU = c
 	ram.PPU = p
+	ram.APU = a
 	ram.Ctrl = ctrl
 
 	return c
@@ -81,14 +83,25 @@ func (cpu *CPU) Vectors() [3]int {
 // ExecNext returns cycle count the whole operation took and an error if one
 // occured.
 func (cpu *CPU) ExecNext() (cycles int, err error) {
-	defer cpu.handleInterrupts()
+	var (
+		op    OpCode
+		prevI = cpu.Reg.I
+	)
+	defer func() { cpu.handleInterrupts(cpu.pollIRQ(op, prevI)) }()
+
+	// Sample fetches by the DMC halt the CPU. As the APU is clocked after the
+	// opcode that was running during the fetch, the stall is accounted for in
+	// the following one.
+	stall := cpu.RAM.APU.DMAStall()
+	cpu.RAM.dmaConflict = stall > 0
+	defer func() { cpu.RAM.dmaConflict = false }()
 
 	code, err := cpu.RAM.Read(cpu.Reg.PC)
 	if err != nil {
 		return 0, errors.Wrap(err, "Failed to read opcode from memory")
 	}
 
-	op := OpCodes[code]
+	op = OpCodes[code]
 	if op.Name == "" {
 		return 0, errors.Errorf("Invalid opcode to execute: %02x, PC: %04x",
 			code, cpu.Reg.PC)
@@ -125,6 +138,8 @@ func (cpu *CPU) ExecNext() (cycles int, err error) {
 		}
 	}
 
+	cycles += stall
+
 	if cycles%2 == 1 {
 		cpu.oddCycle = !cpu.oddCycle
 	}
@@ -133,30 +148,27 @@ func (cpu *CPU) ExecNext() (cycles int, err error) {
 }
 
 // resetPC sets the cpu's PC to the reset vector.
+//
+// The I flag is set as well, masking interrupts (such as the APU's frame
+// interrupt) until the programme clears it.
 func (cpu *CPU) resetPC() {
 	cpu.Reg.PC = int(cpu.RAM.MustRead(ResetVector)) |
 		int(cpu.RAM.MustRead(ResetVector+1))<<8
+	cpu.Reg.I = set
 }
 
 // TODO: Make interrupt handlers constant
-func (cpu *CPU) handleInterrupts() {
+func (cpu *CPU) handleInterrupts(irq bool) {
 	if cpu.reset {
-		cpu.interrupt(ResetVector)
+		cpu.interrupt(ResetVector, false)
 		cpu.reset = false
 	} else if cpu.nmi {
-		cpu.interrupt(NMIVector)
+		cpu.interrupt(NMIVector, false)
 		cpu.nmi = false
-	} else if cpu.irq {
-		cpu.interrupt(IRQVector)
-		cpu.irq = false
-	}
-}
-
-// IRQ causes the IRQ handler to be executed right after the current opcode
-// finished execution, as long as the 'I' bit of the status register is reset.
-func (cpu *CPU) IRQ() {
-	if cpu.Reg.I == clear {
-		cpu.irq = true
+	} else if irq {
+		// The IRQ line isn't released by servicing it, the handler is
+		// expected to acknowledge the interrupt at its source
+		cpu.interrupt(IRQVector, false)
 	}
 }
 
@@ -172,13 +184,22 @@ func (cpu *CPU) Reset() {
 	cpu.reset = true
 }
 
-func (cpu *CPU) interrupt(handlerAddr int) {
+// interrupt pushes PC and P to the stack and jumps to the handler at
+// handlerAddr.
+//
+// The B flag is only set in the pushed P for BRK, letting handlers tell it
+// apart from hardware interrupts.
+func (cpu *CPU) interrupt(handlerAddr int, brk bool) {
 	// push PCH
 	cpu.push(byte(cpu.Reg.PC >> 8))
 	// push PCL
 	cpu.push(byte(cpu.Reg.PC & 0xff))
 	// push P
-	cpu.push(cpu.Reg.GetP())
+	p := cpu.Reg.GetP()
+	if !brk {
+		p &^= 1 << 4
+	}
+	cpu.push(p)
 
 	// TODO: Find appropriate place for this outside the interrupt function so
 	// the CPU can call it when initiating PC in the beginning.
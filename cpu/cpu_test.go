@@ -0,0 +1,123 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/m4ntis/bones/apu"
+	"github.com/m4ntis/bones/ines"
+	"github.com/m4ntis/bones/io"
+	"github.com/m4ntis/bones/ppu"
+)
+
+// testMapper maps a flat, writable memory to the cartridge space, recording
+// the writes made to it.
+type testMapper struct {
+	mem    [RAMSize]byte
+	writes []testWrite
+}
+
+type testWrite struct {
+	addr int
+	d    byte
+}
+
+func (m *testMapper) Read(addr int) (byte, error) {
+	return m.mem[addr], nil
+}
+
+func (m *testMapper) Write(addr int, d byte) error {
+	m.mem[addr] = d
+	m.writes = append(m.writes, testWrite{addr, d})
+	return nil
+}
+
+func (m *testMapper) Observe(addr int) (byte, error) {
+	return m.mem[addr], nil
+}
+
+func (m *testMapper) Nametables() ines.NametableMapping {
+	return ines.MirroredNametables(ines.HorizontalMirroring)
+}
+
+func (m *testMapper) Populate([]ines.PrgROMPage, []ines.ChrROMPage) {}
+
+func (m *testMapper) GetPRGRom() []ines.PrgROMPage {
+	return nil
+}
+
+// newTestCPU creates a CPU running prg from $8000, with a PPU, an APU reading
+// from the CPU's memory and a controller.
+func newTestCPU(prg ...byte) (c *CPU, m *testMapper, a *apu.APU,
+	ctrl *io.Controller) {
+
+	a = apu.New(nil)
+	ctrl = new(io.Controller)
+	c = New(ppu.New(nil), a, ctrl)
+	a.Mem = c.RAM
+
+	m = &testMapper{}
+	copy(m.mem[0x8000:], prg)
+	c.RAM.Mapper = m
+
+	c.Reg.PC = 0x8000
+	c.Reg.SP = 0xff
+	c.Reg.I = set
+
+	return c, m, a, ctrl
+}
+
+// execN executes n opcodes, returning the cycles the last one took.
+func execN(t *testing.T, c *CPU, n int) (cycles int) {
+	for i := 0; i < n; i++ {
+		var err error
+		cycles, err = c.ExecNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cycles
+}
+
+// startDMCFetch makes the DMC fetch a sample byte, stalling the CPU.
+func startDMCFetch(c *CPU, a *apu.APU) {
+	// A sample of a single byte at $c000
+	c.RAM.MustWrite(0x4013, 0)
+	c.RAM.MustWrite(0x4015, 0x10)
+	a.Cycle()
+}
+
+func TestDMAStall(t *testing.T) {
+	// LDA $0200; LDA $0200
+	c, _, a, _ := newTestCPU(0xad, 0x00, 0x02, 0xad, 0x00, 0x02)
+
+	startDMCFetch(c, a)
+	if cycles := execN(t, c, 1); cycles != 4+4 {
+		t.Errorf("Stalled LDA took %d cycles, want 8", cycles)
+	}
+
+	// The stall is only accounted for once
+	if cycles := execN(t, c, 1); cycles != 4 {
+		t.Errorf("LDA after the stall took %d cycles, want 4", cycles)
+	}
+}
+
+func TestDMAControllerConflict(t *testing.T) {
+	// LDA $4016; LDA $4016
+	c, _, a, ctrl := newTestCPU(0xad, 0x16, 0x40, 0xad, 0x16, 0x40)
+	ctrl.PressB()
+
+	// The read halted by the fetch is repeated, clocking the controller twice
+	// and dropping A's state
+	startDMCFetch(c, a)
+	execN(t, c, 1)
+	if c.Reg.A != 1 {
+		t.Errorf("Read $%02x from $4016 during the fetch, want B's 1", c.Reg.A)
+	}
+
+	// Select is read next, as the conflict only affects the halted read
+	execN(t, c, 1)
+	if c.Reg.A != 0 {
+		t.Errorf("Read $%02x from $4016 after the fetch, want select's 0",
+			c.Reg.A)
+	}
+}
@@ -0,0 +1,73 @@
+package cpu
+
+// IRQSource identifies a device driving the CPU's IRQ line.
+//
+// The IRQ line is level triggered and shared by all sources. A source keeps
+// the line asserted until the device's interrupt is acknowledged, and the CPU
+// keeps servicing the interrupt for as long as any of the sources assert it.
+type IRQSource int
+
+const (
+	IRQFrameCounter IRQSource = 1 << iota
+	IRQDMC
+	IRQMapper
+)
+
+var irqSourceNames = map[IRQSource]string{
+	IRQFrameCounter: "frame counter",
+	IRQDMC:          "dmc",
+	IRQMapper:       "mapper",
+}
+
+func (src IRQSource) String() string {
+	name, ok := irqSourceNames[src]
+	if !ok {
+		return "unknown"
+	}
+	return name
+}
+
+// AssertIRQ pulls the IRQ line low on behalf of src.
+func (cpu *CPU) AssertIRQ(src IRQSource) {
+	cpu.irq |= src
+}
+
+// ReleaseIRQ stops src from asserting the IRQ line. The line stays asserted if
+// other sources still assert it.
+func (cpu *CPU) ReleaseIRQ(src IRQSource) {
+	cpu.irq &^= src
+}
+
+// SetIRQ asserts or releases the IRQ line on behalf of src.
+func (cpu *CPU) SetIRQ(src IRQSource, asserted bool) {
+	if asserted {
+		cpu.AssertIRQ(src)
+		return
+	}
+	cpu.ReleaseIRQ(src)
+}
+
+// IRQ returns the sources currently asserting the IRQ line, ORed together.
+func (cpu *CPU) IRQ() IRQSource {
+	return cpu.irq
+}
+
+// pollIRQ returns whether an IRQ should be serviced after the opcode that just
+// finished executing.
+//
+// The 6502 polls interrupts before the last cycle of the opcode, so CLI, SEI
+// and PLP only affect polling after the following opcode, using the I flag's
+// value from before they executed.
+func (cpu *CPU) pollIRQ(op OpCode, prevI byte) bool {
+	if cpu.irq == 0 {
+		return false
+	}
+
+	i := cpu.Reg.I
+	switch op.Name {
+	case "CLI", "SEI", "PLP":
+		i = prevI
+	}
+
+	return i == clear
+}
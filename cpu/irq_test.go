@@ -0,0 +1,161 @@
+package cpu
+
+import (
+	"testing"
+)
+
+const (
+	testIRQHandler = 0x9000
+	testNMIHandler = 0xa000
+
+	nop = 0xea
+	cli = 0x58
+	sei = 0x78
+	plp = 0x28
+	rti = 0x40
+	brk = 0x00
+)
+
+// newTestIRQCPU creates a CPU running prg as newTestCPU does, with the NMI
+// and IRQ handlers being RTIs at testNMIHandler and testIRQHandler.
+func newTestIRQCPU(prg ...byte) (c *CPU, m *testMapper) {
+	c, m, _, _ = newTestCPU(prg...)
+
+	m.mem[NMIVector], m.mem[NMIVector+1] = 0x00, testNMIHandler>>8
+	m.mem[IRQVector], m.mem[IRQVector+1] = 0x00, testIRQHandler>>8
+	m.mem[testNMIHandler] = rti
+	m.mem[testIRQHandler] = rti
+
+	return c, m
+}
+
+func TestIRQMasking(t *testing.T) {
+	tests := []struct {
+		name string
+		prg  []byte
+		i    byte
+		// stackedP is pulled by PLP
+		stackedP byte
+		// serviced is the amount of opcodes after which the IRQ is serviced,
+		// or 0 if it isn't
+		serviced int
+	}{
+		{"I clear", []byte{nop, nop}, clear, 0, 1},
+		{"I set", []byte{nop, nop, nop}, set, 0, 0},
+		{"CLI", []byte{cli, nop, nop}, set, 0, 2},
+		{"SEI", []byte{sei, nop, nop}, clear, 0, 1},
+		{"PLP clearing I", []byte{plp, nop, nop}, set, 0x00, 2},
+		{"PLP setting I", []byte{plp, nop, nop}, clear, 0x04, 1},
+	}
+
+	for _, test := range tests {
+		c, _ := newTestIRQCPU(test.prg...)
+		c.Reg.I = test.i
+		c.push(test.stackedP)
+		c.AssertIRQ(IRQMapper)
+
+		for i := 1; i <= 3; i++ {
+			execN(t, c, 1)
+
+			serviced := c.Reg.PC == testIRQHandler
+			if serviced != (i == test.serviced) {
+				t.Errorf("%s: IRQ serviced %t after %d opcodes, want %t",
+					test.name, serviced, i, i == test.serviced)
+			}
+			if serviced {
+				break
+			}
+		}
+	}
+}
+
+func TestIRQLevelTriggered(t *testing.T) {
+	c, _ := newTestIRQCPU(nop, nop)
+	c.Reg.I = clear
+	c.AssertIRQ(IRQFrameCounter)
+
+	execN(t, c, 1)
+	if c.Reg.PC != testIRQHandler {
+		t.Fatalf("IRQ not serviced, PC is $%04x", c.Reg.PC)
+	}
+
+	// The handler returns without acknowledging the interrupt, so it is
+	// serviced again
+	execN(t, c, 1)
+	if c.Reg.PC != testIRQHandler {
+		t.Errorf("Asserted IRQ not serviced again, PC is $%04x", c.Reg.PC)
+	}
+
+	c.ReleaseIRQ(IRQFrameCounter)
+	execN(t, c, 1)
+	if c.Reg.PC != 0x8001 {
+		t.Errorf("Released IRQ serviced, PC is $%04x, want $8001", c.Reg.PC)
+	}
+}
+
+func TestIRQSources(t *testing.T) {
+	c, _ := newTestIRQCPU(nop, nop)
+	c.Reg.I = clear
+
+	c.AssertIRQ(IRQFrameCounter)
+	c.SetIRQ(IRQMapper, true)
+	c.SetIRQ(IRQDMC, false)
+	if irq := c.IRQ(); irq != IRQFrameCounter|IRQMapper {
+		t.Errorf("IRQ line is asserted by %v, want frame counter and mapper",
+			irq)
+	}
+
+	// The line stays asserted while any source asserts it
+	c.ReleaseIRQ(IRQFrameCounter)
+	execN(t, c, 1)
+	if c.Reg.PC != testIRQHandler {
+		t.Fatalf("IRQ asserted by the mapper not serviced, PC is $%04x",
+			c.Reg.PC)
+	}
+
+	c.SetIRQ(IRQMapper, false)
+	if irq := c.IRQ(); irq != 0 {
+		t.Errorf("IRQ line is asserted by %v after releasing all sources", irq)
+	}
+	execN(t, c, 1)
+	if c.Reg.PC != 0x8001 {
+		t.Errorf("Released IRQ serviced, PC is $%04x, want $8001", c.Reg.PC)
+	}
+}
+
+func TestInterruptBFlag(t *testing.T) {
+	tests := []struct {
+		name    string
+		prg     []byte
+		trigger func(c *CPU)
+		handler int
+		b       byte
+	}{
+		{"BRK", []byte{brk, nop}, func(c *CPU) {}, testIRQHandler, 1},
+		{"IRQ", []byte{nop}, func(c *CPU) { c.AssertIRQ(IRQDMC) },
+			testIRQHandler, 0},
+		{"NMI", []byte{nop}, func(c *CPU) { c.NMI() }, testNMIHandler, 0},
+	}
+
+	for _, test := range tests {
+		c, _ := newTestIRQCPU(test.prg...)
+		c.Reg.I = clear
+		test.trigger(c)
+
+		execN(t, c, 1)
+		if c.Reg.PC != test.handler {
+			t.Errorf("%s: PC is $%04x, want $%04x", test.name, c.Reg.PC,
+				test.handler)
+			continue
+		}
+
+		// P is pushed below the return address, with bit 5 always set
+		p := c.RAM.MustRead(0x1fd)
+		if p&0x20 == 0 || p>>4&1 != test.b {
+			t.Errorf("%s: pushed P is %08b, want B %d", test.name, p, test.b)
+		}
+		if c.Reg.I != set {
+			t.Errorf("%s: I isn't set in the handler", test.name)
+		}
+	}
+}
@@ -55,14 +55,15 @@ func AND(cpu *CPU, op Operand) (extraCycles int) {
 }
 
 func ASL(cpu *CPU, op Operand) (extraCycles int) {
-	d := op.Read()
+	orig := op.Read()
+	d := orig
 
 	cpu.Reg.C = d >> 7
 	d <<= 1
 
 	setNZ(cpu, d)
 
-	extraCycles += op.Write(d)
+	extraCycles += writeModified(op, orig, d)
 	return
 }
 
@@ -167,7 +168,9 @@ func BPL(cpu *CPU, op Operand) (extraCycles int) {
 
 func BRK(cpu *CPU, op Operand) (extraCycles int) {
 	cpu.Reg.PC++
-	cpu.IRQ()
+
+	// BRK isn't masked by the I flag, and doesn't go through the IRQ line
+	cpu.interrupt(IRQVector, true)
 	return
 }
 
@@ -262,9 +265,10 @@ func CPY(cpu *CPU, op Operand) (extraCycles int) {
 }
 
 func DEC(cpu *CPU, op Operand) (extraCycles int) {
-	d := op.Read() - 1
+	orig := op.Read()
+	d := orig - 1
 	setNZ(cpu, d)
-	extraCycles += op.Write(d)
+	extraCycles += writeModified(op, orig, d)
 	return
 }
 
@@ -287,9 +291,10 @@ func EOR(cpu *CPU, op Operand) (extraCycles int) {
 }
 
 func INC(cpu *CPU, op Operand) (extraCycles int) {
-	d := op.Read() + 1
+	orig := op.Read()
+	d := orig + 1
 	setNZ(cpu, d)
-	extraCycles += op.Write(d)
+	extraCycles += writeModified(op, orig, d)
 	return
 }
 
@@ -342,14 +347,15 @@ func LDY(cpu *CPU, op Operand) (extraCycles int) {
 }
 
 func LSR(cpu *CPU, op Operand) (extraCycles int) {
-	d := op.Read()
+	orig := op.Read()
+	d := orig
 
 	cpu.Reg.C = d & 1
 	d >>= 1
 
 	setNZ(cpu, d)
 
-	extraCycles += op.Write(d)
+	extraCycles += writeModified(op, orig, d)
 	return
 }
 
@@ -385,7 +391,8 @@ func PLP(cpu *CPU, op Operand) (extraCycles int) {
 }
 
 func ROL(cpu *CPU, op Operand) (extraCycles int) {
-	d := op.Read()
+	orig := op.Read()
+	d := orig
 
 	carry := cpu.Reg.C
 	cpu.Reg.C = d >> 7
@@ -395,12 +402,13 @@ func ROL(cpu *CPU, op Operand) (extraCycles int) {
 
 	setNZ(cpu, d)
 
-	extraCycles += op.Write(d)
+	extraCycles += writeModified(op, orig, d)
 	return
 }
 
 func ROR(cpu *CPU, op Operand) (extraCycles int) {
-	d := op.Read()
+	orig := op.Read()
+	d := orig
 
 	carry := cpu.Reg.C
 	cpu.Reg.C = d & 1
@@ -410,7 +418,7 @@ func ROR(cpu *CPU, op Operand) (extraCycles int) {
 
 	setNZ(cpu, d)
 
-	extraCycles += op.Write(d)
+	extraCycles += writeModified(op, orig, d)
 	return
 }
 
@@ -532,3 +540,26 @@ func setNZ(cpu *CPU, d byte) {
 	}
 	cpu.Reg.Z = clear
 }
+
+// writeModified writes the result of a read-modify-write operation.
+//
+// Like the 6502, the unmodified value is written back to memory before the
+// result, on the cycle before it, which is visible to memory mapped registers
+// such as the MMC1's. The cycles taken by both writes are counted, as each
+// write to OAMDMA starts a DMA.
+func writeModified(op Operand, orig, d byte) (extraCycles int) {
+	ramOp, ok := op.(RAMOperand)
+	if !ok {
+		return op.Write(d)
+	}
+
+	extraCycles = ramOp.Write(orig)
+
+	// TODO: Note the todo in RAMOperand's Read
+	cycles, err := ramOp.RAM.writeConsecutive(ramOp.Addr, d)
+	if err != nil {
+		panic(err)
+	}
+
+	return extraCycles + cycles
+}
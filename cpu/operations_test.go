@@ -0,0 +1,84 @@
+package cpu
+
+import (
+	"reflect"
+	"testing"
+)
+
+// consecutiveTestMapper is a testMapper recording consecutive writes apart.
+type consecutiveTestMapper struct {
+	testMapper
+	consecutive []testWrite
+}
+
+func (m *consecutiveTestMapper) WriteConsecutive(addr int, d byte) error {
+	m.consecutive = append(m.consecutive, testWrite{addr, d})
+	return nil
+}
+
+func TestReadModifyWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   byte
+		result byte
+	}{
+		{"ASL", 0x0e, 0x82 << 1 & 0xff},
+		{"LSR", 0x4e, 0x82 >> 1},
+		{"ROL", 0x2e, 0x82<<1&0xff | 1},
+		{"ROR", 0x6e, 0x82>>1 | 0x80},
+		{"INC", 0xee, 0x83},
+		{"DEC", 0xce, 0x81},
+	}
+
+	for _, test := range tests {
+		// The opcode on $6000, with the carry set
+		c, m, _, _ := newTestCPU(test.code, 0x00, 0x60)
+		c.Reg.C = set
+		m.mem[0x6000] = 0x82
+
+		if cycles := execN(t, c, 1); cycles != 6 {
+			t.Errorf("%s: took %d cycles, want 6", test.name, cycles)
+		}
+
+		// The unmodified value is written back before the result
+		want := []testWrite{{0x6000, 0x82}, {0x6000, test.result}}
+		if !reflect.DeepEqual(m.writes, want) {
+			t.Errorf("%s: wrote %v, want %v", test.name, m.writes, want)
+		}
+	}
+}
+
+func TestReadModifyWriteConsecutive(t *testing.T) {
+	// INC $8000; INC $0200
+	c, _, _, _ := newTestCPU(0xee, 0x00, 0x80, 0xee, 0x00, 0x02)
+	m := &consecutiveTestMapper{testMapper: *c.RAM.Mapper.(*testMapper)}
+	c.RAM.Mapper = m
+
+	// The result's write is signalled as consecutive to the mapper
+	execN(t, c, 1)
+	if want := []testWrite{{0x8000, 0xee}}; !reflect.DeepEqual(m.writes,
+		want) {
+		t.Errorf("Wrote %v, want %v", m.writes, want)
+	}
+	if want := []testWrite{{0x8000, 0xef}}; !reflect.DeepEqual(m.consecutive,
+		want) {
+		t.Errorf("Wrote %v consecutively, want %v", m.consecutive, want)
+	}
+
+	// Internal RAM isn't affected
+	c.RAM.MustWrite(0x200, 0x10)
+	execN(t, c, 1)
+	if d := c.RAM.MustRead(0x200); d != 0x11 {
+		t.Errorf("INC $0200 wrote $%02x, want $11", d)
+	}
+}
+
+func TestReadModifyWriteDMA(t *testing.T) {
+	c, _, _, _ := newTestCPU()
+
+	// Both writes to OAMDMA start a DMA, each taking 513 cycles
+	op := RAMOperand{RAM: c.RAM, Addr: oamDMAAddr}
+	if cycles := writeModified(op, 0x02, 0x03); cycles != 2*513 {
+		t.Errorf("Writes to OAMDMA took %d cycles, want %d", cycles, 2*513)
+	}
+}
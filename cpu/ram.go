@@ -1,6 +1,7 @@
 package cpu
 
 import (
+	"github.com/m4ntis/bones/apu"
 	"github.com/m4ntis/bones/ines"
 	"github.com/m4ntis/bones/io"
 	"github.com/m4ntis/bones/ppu"
@@ -29,8 +30,11 @@ const (
 	ppuScrollAddr = 0x2005
 	ppuAddrAddr   = 0x2006
 	ppuDataAddr   = 0x2007
+	apuRegAddr    = 0x4000
 	oamDMAAddr    = 0x4014
+	apuStatusAddr = 0x4015
 	ctrl1Addr     = 0x4016
+	apuFrameAddr  = 0x4017
 )
 
 // RAM holds the mos 6502's 16k (64 when mirrored) of on chip memory.
@@ -46,7 +50,13 @@ type RAM struct {
 
 	CPU  *CPU
 	PPU  *ppu.PPU
+	APU  *apu.APU
 	Ctrl *io.Controller
+
+	// dmaConflict is set while a DMC sample fetch halts the CPU. The CPU
+	// repeats the read it was halted on, so reads with side effects happen
+	// twice.
+	dmaConflict bool
 }
 
 // stripMirror returns the underlying address after mirroring.
@@ -82,10 +92,23 @@ func (r *RAM) readMMIO(addr int) (d byte, err error) {
 	case ppuAddrAddr:
 		return 0, errors.New("Invalid read from PPUAddr")
 	case ppuDataAddr:
+		if r.dmaConflict {
+			// The repeated read increments the VRAM address an extra time
+			r.PPU.Regs.PPUDataRead()
+			r.dmaConflict = false
+		}
 		d = r.PPU.Regs.PPUDataRead()
 	case oamDMAAddr:
 		return 0, errors.New("Invalid read from OAMDMA")
+	case apuStatusAddr:
+		d = r.APU.StatusRead()
 	case ctrl1Addr:
+		if r.dmaConflict {
+			// The repeated read clocks the controller's shift register,
+			// dropping a button
+			r.Ctrl.Read()
+			r.dmaConflict = false
+		}
 		d = r.Ctrl.Read()
 	default:
 		// Read from PPU i/o register mirroring
@@ -132,6 +155,13 @@ func (r *RAM) writeMMIO(addr int, d byte) (cycles int, err error) {
 		}
 	case ctrl1Addr:
 		r.Ctrl.Strobe(d & 1)
+	case apuStatusAddr, apuFrameAddr:
+		r.APU.Write(addr, d)
+	default:
+		// APU channel registers
+		if addr >= apuRegAddr && addr < oamDMAAddr {
+			r.APU.Write(addr, d)
+		}
 	}
 
 	// r.data is updated regardless of i/o reg write in order to be able to
@@ -193,6 +223,19 @@ func (r *RAM) Write(addr int, d byte) (cycles int, err error) {
 	return 0, nil
 }
 
+// writeConsecutive puts a value to memory like Write, for a write on the cycle
+// right after a previous write, which cartridges implementing
+// ines.ConsecutiveWriteMapper handle differently.
+func (r *RAM) writeConsecutive(addr int, d byte) (cycles int, err error) {
+	if stripMirror(addr) >= cartridgeSpaceAddr {
+		if m, ok := r.Mapper.(ines.ConsecutiveWriteMapper); ok {
+			return 0, m.WriteConsecutive(stripMirror(addr), d)
+		}
+	}
+
+	return r.Write(addr, d)
+}
+
 // MustWrite calls Write but panics instead of returning an error.
 func (r *RAM) MustWrite(addr int, d byte) (cycles int) {
 	cycles, err := r.Write(addr, d)
@@ -28,7 +28,8 @@ type Registers struct {
 }
 
 // GetP returns the value of the P register, calculated from all the status
-// bit registers. Bits 4 and 5 are hardcoded to be set.
+// bit registers. Bits 4 and 5 are hardcoded to be set, as pushed by PHP and
+// BRK.
 func (reg *Registers) GetP() byte {
 	return reg.C | reg.Z<<1 | reg.I<<2 | reg.D<<3 | 1<<4 | 1<<5 | reg.V<<6 |
 		reg.N<<7
@@ -0,0 +1,193 @@
+package ines
+
+import (
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// embeddedDatabase is the game database built into BoNES, in the format of
+// the NES 2.0 XML database (nes20db.xml).
+//
+//go:embed database.xml
+var embeddedDatabase []byte
+
+// dbGame is a game's record in the database, describing its cartridge as an
+// NES 2.0 header would.
+//
+// The record's title is the comment preceding its elements, usually the ROM's
+// file path, and its board name is an extension of nes20db's pcb element.
+type dbGame struct {
+	Comment string `xml:",comment"`
+
+	PrgROM dbROM `xml:"prgrom"`
+	ChrROM dbROM `xml:"chrrom"`
+	// ROM is the PRG and CHR ROM concatenated, which the game is looked up by
+	ROM dbROM `xml:"rom"`
+
+	PrgRAM   dbRAM `xml:"prgram"`
+	PrgNVRAM dbRAM `xml:"prgnvram"`
+	ChrRAM   dbRAM `xml:"chrram"`
+	ChrNVRAM dbRAM `xml:"chrnvram"`
+
+	PCB struct {
+		Mapper    int    `xml:"mapper,attr"`
+		Submapper int    `xml:"submapper,attr"`
+		Mirroring string `xml:"mirroring,attr"`
+		Battery   int    `xml:"battery,attr"`
+		Board     string `xml:"board,attr"`
+	} `xml:"pcb"`
+
+	Console struct {
+		Type   int `xml:"type,attr"`
+		Region int `xml:"region,attr"`
+	} `xml:"console"`
+
+	Vs *struct {
+		Hardware int `xml:"hardware,attr"`
+		PPU      int `xml:"ppu,attr"`
+	} `xml:"vs"`
+
+	MiscROM struct {
+		Number int `xml:"number,attr"`
+	} `xml:"miscrom"`
+
+	Expansion struct {
+		Type int `xml:"type,attr"`
+	} `xml:"expansion"`
+}
+
+type dbROM struct {
+	Size  int    `xml:"size,attr"`
+	CRC32 string `xml:"crc32,attr"`
+	SHA1  string `xml:"sha1,attr"`
+}
+
+type dbRAM struct {
+	Size int `xml:"size,attr"`
+}
+
+// title returns the game's title, taken from the record's comment with the
+// file path's directories and extension trimmed.
+func (g *dbGame) title() string {
+	title := strings.TrimSpace(g.Comment)
+	title = path.Base(strings.Replace(title, "\\", "/", -1))
+	return strings.TrimSuffix(title, path.Ext(title))
+}
+
+// apply overrides the header's fields with the record's, given the size in
+// bytes of the ROM's PRG and CHR data. The header becomes an NES 2.0 header,
+// as the record specifies all of its fields.
+//
+// The split between PRG and CHR ROM is only taken from the record if it adds
+// up to the ROM's size.
+func (g *dbGame) apply(header *INESHeader, romSize int) {
+	if g.PrgROM.Size > 0 && g.PrgROM.Size+g.ChrROM.Size == romSize {
+		header.prgROMBytes = g.PrgROM.Size
+		header.chrROMBytes = g.ChrROM.Size
+		header.PrgROMSize = (g.PrgROM.Size + PrgROMPageSize - 1) /
+			PrgROMPageSize
+		header.ChrROMSize = (g.ChrROM.Size + ChrROMPageSize - 1) /
+			ChrROMPageSize
+	}
+
+	header.NES20 = true
+	header.MapperNumber = g.PCB.Mapper
+	header.Submapper = g.PCB.Submapper
+	header.PersistentMemory = g.PCB.Battery
+
+	switch g.PCB.Mirroring {
+	case "H":
+		header.Mirroring = HorizontalMirroring
+		header.IgnoreMirror = 0
+	case "V":
+		header.Mirroring = VerticalMirroring
+		header.IgnoreMirror = 0
+	case "4":
+		header.IgnoreMirror = 1
+	}
+
+	header.PrgRAMSize = g.PrgRAM.Size
+	header.PrgNVRAMSize = g.PrgNVRAM.Size
+	header.ChrRAMSize = g.ChrRAM.Size
+	header.ChrNVRAMSize = g.ChrNVRAM.Size
+
+	header.ConsoleType = g.Console.Type
+	header.Timing = g.Console.Region
+	if g.Vs != nil {
+		header.VsHardwareType = g.Vs.Hardware
+		header.VsPPUType = g.Vs.PPU
+	}
+	header.MiscROMs = g.MiscROM.Number
+	header.ExpansionDevice = g.Expansion.Type
+}
+
+var (
+	gamesBySHA1  = map[string]*dbGame{}
+	gamesByCRC32 = map[string]*dbGame{}
+	gamesMu      sync.RWMutex
+)
+
+// LoadDatabase adds the games in an NES 2.0 XML database read from r to the
+// database ROMs are looked up in, replacing the records of games already in
+// it.
+//
+// Games are looked up by the SHA-1, or CRC32 if missing, of their rom
+// element, being the ROM's PRG and CHR data.
+func LoadDatabase(r io.Reader) error {
+	var db struct {
+		Games []*dbGame `xml:"game"`
+	}
+	if err := xml.NewDecoder(r).Decode(&db); err != nil {
+		return errors.Wrap(err, "Error while parsing game database")
+	}
+
+	gamesMu.Lock()
+	defer gamesMu.Unlock()
+
+	for _, g := range db.Games {
+		if g.ROM.SHA1 != "" {
+			gamesBySHA1[strings.ToUpper(g.ROM.SHA1)] = g
+		}
+		if g.ROM.CRC32 != "" {
+			gamesByCRC32[strings.ToUpper(g.ROM.CRC32)] = g
+		}
+	}
+
+	return nil
+}
+
+// lookupGame returns the database's record of a ROM given its PRG and CHR
+// data, or nil if it isn't in the database.
+func lookupGame(data []byte) *dbGame {
+	gamesMu.RLock()
+	defer gamesMu.RUnlock()
+
+	sum := sha1.Sum(data)
+	if g, ok := gamesBySHA1[strings.ToUpper(hex.EncodeToString(sum[:]))]; ok {
+		return g
+	}
+
+	g, ok := gamesByCRC32[fmt.Sprintf("%08X", crc32.ChecksumIEEE(data))]
+	if ok && g.ROM.SHA1 == "" {
+		return g
+	}
+	return nil
+}
+
+func init() {
+	err := LoadDatabase(bytes.NewReader(embeddedDatabase))
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,262 @@
+package ines
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+// testDatabase is a database of games looked up by sha1, by crc32 alone, and
+// by a crc32 alongside a sha1 that doesn't match, formatted with the hashes of
+// their ROMs.
+const testDatabase = `<?xml version="1.0" encoding="UTF-8"?>
+<nes20db>
+	<game>
+		<!-- Games\By SHA-1 (USA).nes -->
+		<prgrom size="16384" crc32="00000000" sha1="0000000000000000000000000000000000000000"/>
+		<chrrom size="8192" crc32="00000000" sha1="0000000000000000000000000000000000000000"/>
+		<rom size="24576" crc32="%[1]s" sha1="%[2]s"/>
+		<prgram size="8192"/>
+		<pcb mapper="4" submapper="1" mirroring="V" battery="1" board="NES-TSROM"/>
+		<console type="0" region="1"/>
+		<expansion type="1"/>
+	</game>
+	<game>
+		<!-- Games/By CRC32 (Japan).nes -->
+		<rom size="24576" crc32="%[3]s"/>
+		<pcb mapper="16" submapper="4" mirroring="4" battery="0" board="BANDAI-FCG"/>
+		<console type="1" region="0"/>
+		<vs hardware="2" ppu="3"/>
+	</game>
+	<game>
+		<!-- Games/Mismatched SHA-1.nes -->
+		<rom size="24576" crc32="%[4]s" sha1="0123456789abcdef0123456789abcdef01234567"/>
+		<pcb mapper="1" submapper="0" mirroring="H" battery="0" board="NES-SNROM"/>
+		<console type="0" region="0"/>
+	</game>
+</nes20db>
+`
+
+// testROMData returns 16k of PRG and 8k of CHR ROM data, filled with fill.
+func testROMData(fill byte) []byte {
+	return bytes.Repeat([]byte{fill}, PrgROMPageSize+ChrROMPageSize)
+}
+
+// loadTestDatabase replaces the game database with testDatabase, given the
+// ROMs looked up by sha1, by crc32 and by a mismatched sha1, and returns a
+// function restoring the previous database.
+func loadTestDatabase(t *testing.T, bySHA1, byCRC32, mismatched []byte) (
+	restore func()) {
+
+	gamesMu.Lock()
+	prevSHA1, prevCRC32 := gamesBySHA1, gamesByCRC32
+	gamesBySHA1, gamesByCRC32 = map[string]*dbGame{}, map[string]*dbGame{}
+	gamesMu.Unlock()
+
+	restore = func() {
+		gamesMu.Lock()
+		gamesBySHA1, gamesByCRC32 = prevSHA1, prevCRC32
+		gamesMu.Unlock()
+	}
+
+	crc := func(data []byte) string {
+		return fmt.Sprintf("%08x", crc32.ChecksumIEEE(data))
+	}
+	// The database's sha1 is compared case insensitively
+	sha := strings.ToUpper(fmt.Sprintf("%x", sha1.Sum(bySHA1)))
+
+	db := fmt.Sprintf(testDatabase, crc(bySHA1), sha, crc(byCRC32),
+		crc(mismatched))
+	if err := LoadDatabase(strings.NewReader(db)); err != nil {
+		restore()
+		t.Fatal(err)
+	}
+
+	return restore
+}
+
+func TestLookupGame(t *testing.T) {
+	bySHA1, byCRC32, mismatched := testROMData(1), testROMData(2),
+		testROMData(3)
+	defer loadTestDatabase(t, bySHA1, byCRC32, mismatched)()
+
+	tests := []struct {
+		name  string
+		data  []byte
+		title string
+	}{
+		{"sha1", bySHA1, "By SHA-1 (USA)"},
+		{"crc32 without sha1", byCRC32, "By CRC32 (Japan)"},
+		{"crc32 with mismatched sha1", mismatched, ""},
+		{"unknown", testROMData(4), ""},
+	}
+
+	for _, test := range tests {
+		g := lookupGame(test.data)
+		switch {
+		case g == nil && test.title != "":
+			t.Errorf("%s: game not found", test.name)
+		case g != nil && test.title == "":
+			t.Errorf("%s: found %q", test.name, g.title())
+		case g != nil && g.title() != test.title:
+			t.Errorf("%s: found %q, want %q", test.name, g.title(), test.title)
+		}
+	}
+}
+
+func TestLookupGameSHA1First(t *testing.T) {
+	bySHA1 := testROMData(1)
+
+	// The second record, without a sha1, has the first's crc32
+	defer loadTestDatabase(t, bySHA1, bySHA1, testROMData(3))()
+
+	g := lookupGame(bySHA1)
+	if g == nil || g.title() != "By SHA-1 (USA)" {
+		t.Errorf("Found %+v, want the game with the matching sha1", g)
+	}
+}
+
+func TestApplyGame(t *testing.T) {
+	bySHA1, byCRC32 := testROMData(1), testROMData(2)
+	defer loadTestDatabase(t, bySHA1, byCRC32, testROMData(3))()
+
+	// An iNES header with garbage in its unused bytes, and PRG and CHR ROM
+	// sizes split differently than the record's
+	header, err := parseHeader(testHeader(1, 1, 0x20, 0x44, 0, 0, 0, 'D',
+		'i', 's', 'k'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	romSize := len(bySHA1)
+
+	byCRC32Header := header
+	lookupGame(byCRC32).apply(&byCRC32Header, romSize)
+
+	lookupGame(bySHA1).apply(&header, romSize)
+	want := INESHeader{
+		PrgROMSize:       1,
+		ChrROMSize:       1,
+		PrgRAMSize:       SRAMSize,
+		Mirroring:        VerticalMirroring,
+		PersistentMemory: 1,
+		MapperNumber:     4,
+		NES20:            true,
+		Submapper:        1,
+		Timing:           TimingPAL,
+		ExpansionDevice:  1,
+		prgROMBytes:      PrgROMPageSize,
+		chrROMBytes:      ChrROMPageSize,
+	}
+	if header != want {
+		t.Errorf("Header is\n%+v, want\n%+v", header, want)
+	}
+
+	// The record without PRG and CHR ROM sizes keeps the header's
+	want = INESHeader{
+		PrgROMSize:     1,
+		ChrROMSize:     1,
+		IgnoreMirror:   1,
+		MapperNumber:   16,
+		NES20:          true,
+		Submapper:      4,
+		ConsoleType:    ConsoleVsSystem,
+		VsPPUType:      3,
+		VsHardwareType: 2,
+	}
+	if byCRC32Header != want {
+		t.Errorf("Header is\n%+v, want\n%+v", byCRC32Header, want)
+	}
+
+	// A split that doesn't add up to the ROM's size is ignored
+	header = INESHeader{PrgROMSize: 2}
+	lookupGame(bySHA1).apply(&header, 2*PrgROMPageSize)
+	if header.PrgROMBytes() != 2*PrgROMPageSize || header.ChrROMBytes() != 0 {
+		t.Errorf("PRG and CHR ROM are %d and %d bytes, want %d and 0",
+			header.PrgROMBytes(), header.ChrROMBytes(), 2*PrgROMPageSize)
+	}
+}
+
+func TestReadDumpDatabase(t *testing.T) {
+	bySHA1 := testROMData(1)
+	defer loadTestDatabase(t, bySHA1, testROMData(2), testROMData(3))()
+
+	// Mapper 0 with horizontal mirroring, corrected by the database
+	file := append(testHeader(1, 1), bySHA1...)
+	dump, err := ReadDump(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !dump.InDatabase || dump.Title != "By SHA-1 (USA)" ||
+		dump.Board != "NES-TSROM" {
+		t.Errorf("Dump is in database %t, titled %q on board %q",
+			dump.InDatabase, dump.Title, dump.Board)
+	}
+	if dump.Header.MapperNumber != 4 ||
+		dump.Header.Mirroring != VerticalMirroring {
+		t.Errorf("Header wasn't corrected, mapper %d mirroring %d",
+			dump.Header.MapperNumber, dump.Header.Mirroring)
+	}
+}
+
+func TestGameTitle(t *testing.T) {
+	tests := []struct {
+		comment string
+		want    string
+	}{
+		{" Games\\Licensed\\Title (USA).nes ", "Title (USA)"},
+		{"Games/Title (Europe) (Rev 1).nes", "Title (Europe) (Rev 1)"},
+		{"Title", "Title"},
+	}
+
+	for _, test := range tests {
+		g := &dbGame{Comment: test.comment}
+		if title := g.title(); title != test.want {
+			t.Errorf("%q: title is %q, want %q", test.comment, title,
+				test.want)
+		}
+	}
+}
+
+func TestEmbeddedDatabase(t *testing.T) {
+	var db struct {
+		Games []*dbGame `xml:"game"`
+	}
+	if err := xml.Unmarshal(embeddedDatabase, &db); err != nil {
+		t.Fatal(err)
+	}
+
+	isHex := func(s string, length int) bool {
+		_, err := hex.DecodeString(s)
+		return len(s) == length && err == nil
+	}
+
+	gamesMu.RLock()
+	defer gamesMu.RUnlock()
+
+	for _, g := range db.Games {
+		name := g.title()
+		if name == "" {
+			t.Errorf("Record of rom %s has no title", g.ROM.SHA1)
+		}
+		if !isHex(g.ROM.SHA1, 40) || !isHex(g.ROM.CRC32, 8) {
+			t.Errorf("%s: rom has sha1 %q and crc32 %q", name, g.ROM.SHA1,
+				g.ROM.CRC32)
+		}
+		if g.ROM.Size != g.PrgROM.Size+g.ChrROM.Size {
+			t.Errorf("%s: rom of %d bytes isn't its PRG and CHR ROM", name,
+				g.ROM.Size)
+		}
+
+		// The embedded records are loaded on init
+		if loaded, ok := gamesBySHA1[strings.ToUpper(g.ROM.SHA1)]; !ok ||
+			loaded.title() != name {
+			t.Errorf("%s: record isn't loaded", name)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package ines
+
+// EEPROM sizes of the serial EEPROMs used by Bandai's boards
+const (
+	eeprom24C01Size = 128
+	eeprom24C02Size = 256
+)
+
+// eepromState is the part of a transfer a serial EEPROM is in.
+type eepromState int
+
+const (
+	// eepromIdle waits for a start condition
+	eepromIdle eepromState = iota
+	// eepromDevice receives the device address byte, or the X24C01's word
+	// address and direction byte
+	eepromDevice
+	// eepromAddress receives the 24C02's word address
+	eepromAddress
+	// eepromWrite receives bytes to write
+	eepromWrite
+	// eepromRead sends bytes to the CPU
+	eepromRead
+)
+
+// eeprom implements the I²C serial EEPROMs on Bandai's boards, the 24C02 and
+// the Xicor X24C01, which is addressed directly after the start condition
+// without a device address, and transfers its bits least significant first.
+//
+// The CPU bit bangs the clock (SCL) and data (SDA) lines. Bits are sampled on
+// the rising edges of SCL, with each byte followed by an acknowledge bit, and
+// changes to SDA while SCL is high signal the start and end of a transfer.
+type eeprom struct {
+	data   []byte
+	x24c01 bool
+
+	scl bool
+	sda bool
+
+	state eepromState
+	bit   int
+	shift byte
+	addr  byte
+
+	// sending is set while the EEPROM sends a byte, until its acknowledge
+	// bit
+	sending bool
+
+	// out is the EEPROM's output on SDA, high when released
+	out bool
+}
+
+// newEEPROM returns an EEPROM of size bytes, which is a 24C02 or an X24C01.
+func newEEPROM(size int, x24c01 bool) eeprom {
+	return eeprom{
+		data:   make([]byte, size),
+		x24c01: x24c01,
+		out:    true,
+	}
+}
+
+// write sets the levels of the SCL and SDA lines.
+func (e *eeprom) write(scl, sda bool) {
+	switch {
+	case e.scl && scl && e.sda && !sda:
+		// Start condition
+		e.state = eepromDevice
+		e.bit = 0
+		e.sending = false
+		e.out = true
+	case e.scl && scl && !e.sda && sda:
+		// Stop condition
+		e.state = eepromIdle
+		e.out = true
+	case !e.scl && scl:
+		e.rise(sda)
+	case e.scl && !scl:
+		e.fall()
+	}
+
+	e.scl = scl
+	e.sda = sda
+}
+
+// rise samples SDA on a rising edge of SCL.
+func (e *eeprom) rise(sda bool) {
+	if e.state == eepromIdle {
+		return
+	}
+
+	if e.bit == 8 {
+		// The acknowledge bit, where the CPU ends a read by not acknowledging
+		if e.sending && sda {
+			e.state = eepromIdle
+		}
+		e.bit++
+		return
+	}
+
+	if !e.sending {
+		var b byte
+		if sda {
+			b = 1
+		}
+
+		if e.x24c01 {
+			e.shift = e.shift>>1 | b<<7
+		} else {
+			e.shift = e.shift<<1 | b
+		}
+	}
+	e.bit++
+}
+
+// fall drives SDA on a falling edge of SCL, for the next bit.
+func (e *eeprom) fall() {
+	if e.state == eepromIdle {
+		return
+	}
+
+	switch {
+	case e.bit == 8:
+		// Acknowledge a received byte, or release SDA for the CPU's
+		// acknowledge
+		e.out = true
+		if !e.sending && e.receive(e.shift) {
+			e.out = false
+		}
+
+	case e.bit == 9:
+		e.bit = 0
+		e.out = true
+		e.sending = e.state == eepromRead
+		if e.sending {
+			e.shift = e.data[e.addr]
+			e.addr = byte((int(e.addr) + 1) % len(e.data))
+			e.out = e.sendBit()
+		}
+
+	case e.sending:
+		e.out = e.sendBit()
+	}
+}
+
+// sendBit returns the next bit to send of the byte being read.
+func (e *eeprom) sendBit() bool {
+	if e.x24c01 {
+		return e.shift>>uint(e.bit)&1 == 1
+	}
+	return e.shift>>uint(7-e.bit)&1 == 1
+}
+
+// receive handles a received byte, returning whether it is acknowledged.
+func (e *eeprom) receive(d byte) (ack bool) {
+	switch e.state {
+	case eepromDevice:
+		if e.x24c01 {
+			e.addr = d & 0x7f
+			e.state = eepromWrite
+			if d>>7 == 1 {
+				e.state = eepromRead
+			}
+			return true
+		}
+
+		// The 24C02's device address is 1010, followed by its 3 chip select
+		// bits and the direction bit
+		if d>>4 != 0xa {
+			e.state = eepromIdle
+			return false
+		}
+		e.state = eepromAddress
+		if d&1 == 1 {
+			e.state = eepromRead
+		}
+
+	case eepromAddress:
+		e.addr = byte(int(d) % len(e.data))
+		e.state = eepromWrite
+
+	case eepromWrite:
+		e.data[e.addr] = d
+
+		// Writes wrap around within a page, of 4 bytes on the X24C01 and 8
+		// bytes on the 24C02
+		page := byte(7)
+		if e.x24c01 {
+			page = 3
+		}
+		e.addr = e.addr&^page | (e.addr+1)&page
+	}
+
+	return true
+}
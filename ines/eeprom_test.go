@@ -0,0 +1,193 @@
+package ines
+
+import (
+	"testing"
+)
+
+// eepromBus bit bangs the I²C lines of a Bandai board's EEPROM through the
+// LZ93D50's register at $800d, reading SDA back from $6000.
+type eepromBus struct {
+	m        Mapper
+	lsbFirst bool
+}
+
+// set sets the levels of the SCL and SDA lines.
+func (b *eepromBus) set(scl, sda bool) {
+	var d byte
+	if scl {
+		d |= 0x20
+	}
+	if sda {
+		d |= 0x40
+	}
+	b.m.Write(0x800d, d)
+}
+
+// sda returns the level of SDA as driven by the EEPROM.
+func (b *eepromBus) sda() bool {
+	d, _ := b.m.Read(0x6000)
+	return d&0x10 != 0
+}
+
+func (b *eepromBus) start() {
+	b.set(false, true)
+	b.set(true, true)
+	b.set(true, false)
+	b.set(false, false)
+}
+
+func (b *eepromBus) stop() {
+	b.set(false, false)
+	b.set(true, false)
+	b.set(true, true)
+}
+
+// clock clocks a bit out on SDA, returning SDA's level while SCL is high.
+func (b *eepromBus) clock(sda bool) bool {
+	b.set(false, sda)
+	b.set(true, sda)
+	level := b.sda()
+	b.set(false, sda)
+
+	return level
+}
+
+// writeByte sends a byte, returning whether the EEPROM acknowledged it.
+func (b *eepromBus) writeByte(d byte) (ack bool) {
+	for i := uint(0); i < 8; i++ {
+		bit := d>>(7-i)&1 == 1
+		if b.lsbFirst {
+			bit = d>>i&1 == 1
+		}
+		b.clock(bit)
+	}
+
+	// SDA is released for the EEPROM's acknowledge
+	return !b.clock(true)
+}
+
+// readByte receives a byte, acknowledging it if more bytes are to be read.
+func (b *eepromBus) readByte(ack bool) (d byte) {
+	for i := uint(0); i < 8; i++ {
+		if !b.clock(true) {
+			continue
+		}
+
+		if b.lsbFirst {
+			d |= 1 << i
+		} else {
+			d |= 1 << (7 - i)
+		}
+	}
+
+	b.clock(!ack)
+	return d
+}
+
+func TestEEPROM(t *testing.T) {
+	tests := []struct {
+		name   string
+		mapper int
+		x24c01 bool
+		size   int
+		page   int
+	}{
+		{"24C02", 16, false, eeprom24C02Size, 8},
+		{"X24C01", 159, true, eeprom24C01Size, 4},
+	}
+
+	for _, test := range tests {
+		m := newTestMapper(test.mapper, 8, 1)
+		bus := &eepromBus{m: m, lsbFirst: test.x24c01}
+
+		// address starts a transfer, selecting the word address
+		address := func(addr byte, read bool) bool {
+			bus.start()
+			if test.x24c01 {
+				d := addr
+				if read {
+					d |= 0x80
+				}
+				return bus.writeByte(d)
+			}
+
+			if !bus.writeByte(0xa0) || !bus.writeByte(addr) {
+				return false
+			}
+			if read {
+				// Restart the transfer in the read direction
+				bus.start()
+				return bus.writeByte(0xa1)
+			}
+			return true
+		}
+
+		// Write a page and a half from the middle of a page, wrapping around
+		// to the page's beginning
+		base := byte(2 * test.page)
+		start := base + 2
+		data := make([]byte, test.page+test.page/2)
+		for i := range data {
+			data[i] = byte(0x40 + i)
+		}
+
+		if !address(start, false) {
+			t.Fatalf("%s: write transfer not acknowledged", test.name)
+		}
+		for i, d := range data {
+			if !bus.writeByte(d) {
+				t.Fatalf("%s: data byte %d not acknowledged", test.name, i)
+			}
+		}
+		bus.stop()
+
+		want := make([]byte, test.page)
+		for i, d := range data {
+			want[(2+i)%test.page] = d
+		}
+
+		// Read the page back sequentially, and the following byte of the next
+		// page which wasn't written
+		if !address(base, true) {
+			t.Fatalf("%s: read transfer not acknowledged", test.name)
+		}
+		for i, w := range append(want, 0) {
+			ack := i < len(want)
+			if d := bus.readByte(ack); d != w {
+				t.Errorf("%s: read $%02x from $%02x, want $%02x", test.name,
+					d, int(base)+i, w)
+			}
+		}
+		bus.stop()
+
+		battery := m.(BatteryMapper).Battery()
+		if len(battery) != test.size {
+			t.Errorf("%s: battery is %d bytes, want %d", test.name,
+				len(battery), test.size)
+		}
+		for i, w := range want {
+			if d := battery[int(base)+i]; d != w {
+				t.Errorf("%s: battery holds $%02x at $%02x, want $%02x",
+					test.name, d, int(base)+i, w)
+			}
+		}
+	}
+}
+
+func TestEEPROMDeviceAddress(t *testing.T) {
+	m := newTestMapper(16, 8, 1)
+	bus := &eepromBus{m: m}
+
+	// The 24C02 ignores transfers to other devices
+	bus.start()
+	if bus.writeByte(0xb0) {
+		t.Error("24C02 acknowledged device address $b0")
+	}
+	bus.stop()
+
+	bus.start()
+	if !bus.writeByte(0xa0) {
+		t.Error("24C02 didn't acknowledge device address $a0")
+	}
+	bus.stop()
+}
@@ -6,25 +6,119 @@ import (
 	"encoding/hex"
 	"io"
 
+	"github.com/m4ntis/bones/region"
 	"github.com/pkg/errors"
 )
 
 const (
 	InesHeaderSize = 16
 
+	// maxROMSize is the largest PRG or CHR ROM size in bytes accepted from
+	// NES 2.0 headers, well above any cartridge's. Larger sizes in their
+	// exponent notation would overflow.
+	maxROMSize = 64 << 20
+
 	HorizontalMirroring = 0
 	VerticalMirroring   = 1
+
+	// Single screen mirroring can only be set by mappers, mapping all
+	// nametables to either the lower or upper one
+	SingleScreenLowerMirroring = 2
+	SingleScreenUpperMirroring = 3
+)
+
+// CPU/PPU timings, the region of the console a ROM is made for
+const (
+	TimingNTSC = iota
+	TimingPAL
+	// TimingMultiRegion ROMs run on both NTSC and PAL consoles
+	TimingMultiRegion
+	TimingDendy
 )
 
+// Console types, where the types from ConsoleFamiclone onwards are NES 2.0's
+// extended console types
+const (
+	ConsoleNES = iota
+	ConsoleVsSystem
+	ConsolePlaychoice10
+	ConsoleFamiclone
+	// ConsoleEPSM is a NES or Famicom with an EPSM sound module
+	ConsoleEPSM
+	ConsoleVT01
+	ConsoleVT02
+	ConsoleVT03
+	ConsoleVT09
+	ConsoleVT32
+	ConsoleVT369
+	ConsoleUM6578
+	ConsoleFamicomNetwork
+)
+
+// INESHeader holds the fields of an iNES or NES 2.0 header.
+//
+// PRG and CHR ROM sizes are in 16k and 8k pages, rounded up for NES 2.0 sizes
+// that aren't whole pages. RAM sizes are in bytes, separating RAM that keeps
+// its contents when the console is off (NVRAM) only in NES 2.0 headers.
 type INESHeader struct {
 	PrgROMSize int
 	ChrROMSize int
 
+	PrgRAMSize   int
+	PrgNVRAMSize int
+	ChrRAMSize   int
+	ChrNVRAMSize int
+
 	Mirroring        int
 	PersistentMemory int
 	Trainer          int
 	IgnoreMirror     int
 	MapperNumber     int
+
+	// NES20 is set if the header is in NES 2.0 format, in which case the
+	// fields below are taken from it, and defaulted otherwise
+	NES20 bool
+
+	Submapper       int
+	Timing          int
+	ConsoleType     int
+	VsPPUType       int
+	VsHardwareType  int
+	MiscROMs        int
+	ExpansionDevice int
+
+	// Exact ROM sizes, in bytes
+	prgROMBytes int
+	chrROMBytes int
+}
+
+// Region returns the region of the console the ROM is made for, where
+// multi-region ROMs run as NTSC.
+func (h INESHeader) Region() region.Region {
+	switch h.Timing {
+	case TimingPAL:
+		return region.PAL
+	case TimingDendy:
+		return region.Dendy
+	default:
+		return region.NTSC
+	}
+}
+
+// PrgROMBytes returns the PRG ROM's size in bytes.
+func (h INESHeader) PrgROMBytes() int {
+	if h.prgROMBytes == 0 {
+		return h.PrgROMSize * PrgROMPageSize
+	}
+	return h.prgROMBytes
+}
+
+// ChrROMBytes returns the CHR ROM's size in bytes.
+func (h INESHeader) ChrROMBytes() int {
+	if h.chrROMBytes == 0 {
+		return h.ChrROMSize * ChrROMPageSize
+	}
+	return h.chrROMBytes
 }
 
 func readHeader(r io.Reader) (header []byte, err error) {
@@ -50,13 +144,6 @@ func parseHeader(headerBuff []byte) (header INESHeader, err error) {
 			hex.Dump(headerBuff[:4]))
 	}
 
-	prgROMSize := headerBuff[4]
-	if prgROMSize == 0 {
-		return INESHeader{}, errors.New("PRG ROM size can't be 0")
-	}
-
-	chrROMSize := headerBuff[5]
-
 	/*
 		Flag 6
 		76543210
@@ -68,44 +155,163 @@ func parseHeader(headerBuff []byte) (header INESHeader, err error) {
 		||||+---- 1: Ignore mirroring control or above mirroring bit; instead provide four-screen VRAM
 		++++----- Lower nybble of mapper number
 	*/
-	mirroring := headerBuff[6] & 1
-	persistentMemory := headerBuff[6] & 2 >> 1
-	trainer := headerBuff[6] & 4 >> 2
-	ignoreMirror := headerBuff[6] & 8 >> 3
-	mapperNumber := headerBuff[6] & 240 >> 4
+	header.Mirroring = int(headerBuff[6] & 1)
+	header.PersistentMemory = int(headerBuff[6] & 2 >> 1)
+	header.Trainer = int(headerBuff[6] & 4 >> 2)
+	header.IgnoreMirror = int(headerBuff[6] & 8 >> 3)
+	header.MapperNumber = int(headerBuff[6] & 240 >> 4)
 
 	/*
 		Flag 7
 		76543210
 		||||||||
-		|||||||+- VS Unisystem
-		||||||+-- PlayChoice-10 (8KB of Hint Screen data stored after CHR data)
+		||||||++- Console type: 0: NES/Famicom, 1: Vs. System, 2: PlayChoice-10,
+		||||||                  3: Extended console type (NES 2.0)
 		||||++--- If equal to 2, flags 8-15 are in NES 2.0 format
 		++++----- Upper nybble of mapper number
 	*/
-	//version := headerBuff[7] & 12 >> 2
-	mapperNumber += headerBuff[7] & 240 >> 4
+	header.ConsoleType = int(headerBuff[7] & 3)
+	header.NES20 = headerBuff[7]&12>>2 == 2
+	header.MapperNumber |= int(headerBuff[7] & 240)
 
-	tvSystem := headerBuff[9] & 1
-	if tvSystem == 1 {
-		return INESHeader{}, errors.Errorf("This is a PAL ROM. BoNES only supports NTSC games")
+	if header.NES20 {
+		if err := parseNES20(headerBuff, &header); err != nil {
+			return INESHeader{}, err
+		}
+	} else {
+		parseINES(headerBuff, &header)
 	}
 
-	return INESHeader{
-		PrgROMSize: int(prgROMSize),
-		ChrROMSize: int(chrROMSize),
+	if header.PrgROMBytes() == 0 {
+		return INESHeader{}, errors.New("PRG ROM size can't be 0")
+	}
 
-		Mirroring:        int(mirroring),
-		PersistentMemory: int(persistentMemory),
-		Trainer:          int(trainer),
-		IgnoreMirror:     int(ignoreMirror),
-		MapperNumber:     int(mapperNumber),
-	}, nil
+	return header, nil
 }
 
-// Parse reads an ines rom from r and populates a ROM struct with its data or
-// returns an error.
-func Parse(r io.Reader) (rom *ROM, err error) {
+// parseINES parses flags 8-15 of an iNES header, which only specify the PRG
+// RAM size and TV system.
+func parseINES(headerBuff []byte, header *INESHeader) {
+	header.PrgROMSize = int(headerBuff[4])
+	header.ChrROMSize = int(headerBuff[5])
+
+	switch header.ConsoleType {
+	case ConsolePlaychoice10:
+		// The Playchoice-10's hint screen is an extra ROM after CHR ROM
+		header.MiscROMs = 1
+	case ConsoleFamiclone:
+		// iNES has no extended console types
+		header.ConsoleType = ConsoleNES
+	}
+
+	// Flag 8 is the PRG RAM size in 8k units, where 0 infers 8k for
+	// compatibility
+	prgRAMBanks := int(headerBuff[8])
+	if prgRAMBanks == 0 {
+		prgRAMBanks = 1
+	}
+	header.PrgRAMSize = prgRAMBanks * SRAMSize
+
+	if header.ChrROMSize == 0 {
+		header.ChrRAMSize = ChrRAMSize
+	}
+
+	header.Timing = int(headerBuff[9] & 1)
+}
+
+// parseNES20 parses flags 8-15 of an NES 2.0 header, or returns an error if
+// its ROM sizes are too large.
+func parseNES20(headerBuff []byte, header *INESHeader) (err error) {
+	/*
+		Flag 8
+		76543210
+		||||||||
+		||||++++- Mapper number bits 8-11
+		++++----- Submapper number
+	*/
+	header.MapperNumber |= int(headerBuff[8]&15) << 8
+	header.Submapper = int(headerBuff[8] >> 4)
+
+	// Flag 9 holds the most significant nybbles of the PRG and CHR ROM sizes
+	header.prgROMBytes, err = nes20ROMSize(headerBuff[4], headerBuff[9]&15,
+		PrgROMPageSize)
+	if err != nil {
+		return errors.Wrap(err, "Invalid PRG ROM size")
+	}
+	header.chrROMBytes, err = nes20ROMSize(headerBuff[5], headerBuff[9]>>4,
+		ChrROMPageSize)
+	if err != nil {
+		return errors.Wrap(err, "Invalid CHR ROM size")
+	}
+	header.PrgROMSize = (header.prgROMBytes + PrgROMPageSize - 1) /
+		PrgROMPageSize
+	header.ChrROMSize = (header.chrROMBytes + ChrROMPageSize - 1) /
+		ChrROMPageSize
+
+	// Flags 10 and 11 are the PRG and CHR RAM sizes, volatile in the low
+	// nybble and non volatile in the high nybble
+	header.PrgRAMSize = nes20RAMSize(headerBuff[10] & 15)
+	header.PrgNVRAMSize = nes20RAMSize(headerBuff[10] >> 4)
+	header.ChrRAMSize = nes20RAMSize(headerBuff[11] & 15)
+	header.ChrNVRAMSize = nes20RAMSize(headerBuff[11] >> 4)
+
+	// Flag 12 is the CPU/PPU timing
+	header.Timing = int(headerBuff[12] & 3)
+
+	// Flag 13 is the Vs. System's PPU and hardware types, or the extended
+	// console type
+	switch header.ConsoleType {
+	case ConsoleVsSystem:
+		header.VsPPUType = int(headerBuff[13] & 15)
+		header.VsHardwareType = int(headerBuff[13] >> 4)
+	case ConsoleFamiclone:
+		header.ConsoleType = int(headerBuff[13] & 15)
+	}
+
+	// Flag 14 is the amount of miscellaneous ROMs after CHR ROM, and flag 15
+	// is the default expansion device
+	header.MiscROMs = int(headerBuff[14] & 3)
+	header.ExpansionDevice = int(headerBuff[15] & 63)
+
+	return nil
+}
+
+// nes20ROMSize returns the size in bytes of an NES 2.0 ROM size, given its
+// least and most significant bytes and its unit.
+//
+// An MSB nybble of $f selects the exponent-multiplier notation, where the LSB
+// holds a 6 bit exponent E and a 2 bit multiplier MM, the size being
+// 2^E * (MM*2 + 1). Sizes above maxROMSize return an error.
+func nes20ROMSize(lsb byte, msb byte, unit int) (size int, err error) {
+	if msb != 15 {
+		return (int(msb)<<8 | int(lsb)) * unit, nil
+	}
+
+	// Large exponents overflow when shifted, so they're checked first
+	exp, mult := uint(lsb>>2), int64(lsb&3*2+1)
+	if exp > 32 || mult<<exp > maxROMSize {
+		return 0, errors.Errorf("2^%d * %d bytes exceeds the maximum of %d bytes",
+			exp, mult, maxROMSize)
+	}
+	return int(mult << exp), nil
+}
+
+// nes20RAMSize returns the size in bytes of an NES 2.0 RAM shift count, being
+// 64 << shift, or 0 if shift is 0.
+func nes20RAMSize(shift byte) int {
+	if shift == 0 {
+		return 0
+	}
+	return 64 << shift
+}
+
+// ReadDump reads an ines file from r into a Dump, without creating the ROM's
+// mapper, or returns an error.
+//
+// ROMs found in the game database by their PRG and CHR data have their header
+// corrected by the database's record, as many dumps have wrong or garbage
+// headers.
+func ReadDump(r io.Reader) (dump *Dump, err error) {
 	// Read and parse header
 	headerBuff, err := readHeader(r)
 	if err != nil {
@@ -116,44 +322,48 @@ func Parse(r io.Reader) (rom *ROM, err error) {
 		return nil, errors.Wrap(err, "Error while parsing iNes header")
 	}
 
-	romMapper, err := NewMapper(header.MapperNumber)
-	if err != nil {
-		return nil, errors.Wrap(err, "Error while parsing iNes rom")
-	}
-
 	// Calculate ROM size and read it
 	trainerSize := header.Trainer * TrainerSize
-	prgROMSize := header.PrgROMSize * PrgROMPageSize
-	chrROMSize := header.ChrROMSize * ChrROMPageSize
+	prgROMSize := header.PrgROMBytes()
+	chrROMSize := header.ChrROMBytes()
 	romSize := trainerSize + prgROMSize + chrROMSize
 
 	romBuff := make([]byte, romSize)
-	n, err := r.Read(romBuff)
-	if n > 0 && n < romSize {
+	n, err := io.ReadFull(r, romBuff)
+	if err == io.ErrUnexpectedEOF {
 		return nil, errors.Errorf("Not enough data in ROM, %d/%d", n, romSize)
 	} else if err != nil {
 		return nil, errors.Wrap(err, "Error while reading ROM")
 	}
 
-	// Populate ROM fields
-	var trainer Trainer
-	copy(trainer[:], romBuff[:trainerSize])
+	dump = &Dump{Header: header}
+
+	if game := lookupGame(romBuff[trainerSize:]); game != nil {
+		game.apply(&dump.Header, prgROMSize+chrROMSize)
+		prgROMSize = dump.Header.PrgROMBytes()
 
-	prgROM := make([]PrgROMPage, header.PrgROMSize)
-	for i := range prgROM {
-		startIndex := trainerSize + i*PrgROMPageSize
-		copy(prgROM[i][:],
-			romBuff[startIndex:startIndex+PrgROMPageSize])
+		dump.InDatabase = true
+		dump.Title = game.title()
+		dump.Board = game.PCB.Board
 	}
 
-	chrROM := make([]ChrROMPage, header.ChrROMSize)
-	for i := range chrROM {
-		startIndex := trainerSize + prgROMSize + i*ChrROMPageSize
-		copy(chrROM[i][:],
-			romBuff[startIndex:startIndex+ChrROMPageSize])
+	if trainerSize > 0 {
+		dump.Trainer = romBuff[:trainerSize]
 	}
+	dump.PrgROM = romBuff[trainerSize : trainerSize+prgROMSize]
+	dump.ChrROM = romBuff[trainerSize+prgROMSize:]
 
-	romMapper.Populate(prgROM, chrROM)
+	return dump, nil
+}
 
-	return &ROM{Header: header, Trainer: trainer, Mapper: romMapper}, nil
+// Parse reads an ines rom from r and populates a ROM struct with its data or
+// returns an error, which it also does for ROMs whose mapper isn't supported.
+//
+// The ROM's header is corrected by the game database as in ReadDump.
+func Parse(r io.Reader) (rom *ROM, err error) {
+	dump, err := ReadDump(r)
+	if err != nil {
+		return nil, err
+	}
+	return dump.ROM()
 }
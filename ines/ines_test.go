@@ -0,0 +1,184 @@
+package ines
+
+import (
+	"bytes"
+	"testing"
+)
+
+// testHeader returns an iNES header with its flags 4-15 set to flags.
+func testHeader(flags ...byte) []byte {
+	h := make([]byte, InesHeaderSize)
+	copy(h, []byte{0x4e, 0x45, 0x53, 0x1a})
+	copy(h[4:], flags)
+	return h
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+		want   INESHeader
+	}{
+		{
+			name: "iNES",
+			// Vertical mirroring, battery, mapper 1, 0 PRG RAM banks, PAL
+			header: testHeader(2, 1, 0x13, 0x00, 0, 1),
+			want: INESHeader{
+				PrgROMSize:       2,
+				ChrROMSize:       1,
+				PrgRAMSize:       SRAMSize,
+				Mirroring:        VerticalMirroring,
+				PersistentMemory: 1,
+				MapperNumber:     1,
+				Timing:           TimingPAL,
+			},
+		},
+		{
+			name: "iNES CHR RAM",
+			// Trainer, four screen, mapper $4a, 2 PRG RAM banks, Playchoice-10
+			header: testHeader(1, 0, 0xac, 0x42, 2),
+			want: INESHeader{
+				PrgROMSize:   1,
+				PrgRAMSize:   2 * SRAMSize,
+				ChrRAMSize:   ChrRAMSize,
+				Trainer:      1,
+				IgnoreMirror: 1,
+				MapperNumber: 0x4a,
+				ConsoleType:  ConsolePlaychoice10,
+				MiscROMs:     1,
+			},
+		},
+		{
+			name: "NES 2.0",
+			// Mapper $a14 submapper 3, $102 PRG and $201 CHR pages, PRG RAM
+			// and NVRAM shifts of 5 and 7, CHR RAM shift of 7, multi-region,
+			// Famicom Network System, one misc ROM, expansion device $3f
+			header: testHeader(0x02, 0x01, 0x40, 0x1b, 0x3a, 0x21, 0x75, 0x07,
+				0x02, 0x0c, 0x01, 0x3f),
+			want: INESHeader{
+				PrgROMSize:      0x102,
+				ChrROMSize:      0x201,
+				PrgRAMSize:      2048,
+				PrgNVRAMSize:    8192,
+				ChrRAMSize:      8192,
+				MapperNumber:    0xa14,
+				NES20:           true,
+				Submapper:       3,
+				Timing:          TimingMultiRegion,
+				ConsoleType:     ConsoleFamicomNetwork,
+				MiscROMs:        1,
+				ExpansionDevice: 0x3f,
+				prgROMBytes:     0x102 * PrgROMPageSize,
+				chrROMBytes:     0x201 * ChrROMPageSize,
+			},
+		},
+		{
+			name: "NES 2.0 exponent sizes",
+			// 2^10 * 3 bytes of PRG and 2^13 bytes of CHR ROM, CHR NVRAM
+			// shift of 9, Dendy, Vs. System with PPU type 1 and hardware 2
+			header: testHeader(10<<2|1, 13<<2, 0x01, 0x09, 0x00, 0xff, 0x00,
+				0x90, 0x03, 0x21),
+			want: INESHeader{
+				PrgROMSize:     1,
+				ChrROMSize:     1,
+				ChrNVRAMSize:   32768,
+				Mirroring:      VerticalMirroring,
+				NES20:          true,
+				Timing:         TimingDendy,
+				ConsoleType:    ConsoleVsSystem,
+				VsPPUType:      1,
+				VsHardwareType: 2,
+				prgROMBytes:    3072,
+				chrROMBytes:    8192,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		header, err := parseHeader(test.header)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if header != test.want {
+			t.Errorf("%s: header is\n%+v, want\n%+v", test.name, header,
+				test.want)
+		}
+	}
+}
+
+func TestParseHeaderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+	}{
+		{"prefix", append([]byte("NES\x00"), make([]byte, 12)...)},
+		{"no PRG ROM", testHeader(0, 1)},
+		{"no NES 2.0 PRG ROM", testHeader(0, 1, 0, 0x08)},
+		{"PRG ROM exponent 63", testHeader(0xff, 0, 0, 0x08, 0, 0x0f)},
+		{"PRG ROM over maximum", testHeader(26<<2|1, 0, 0, 0x08, 0, 0x0f)},
+		{"CHR ROM exponent 40", testHeader(1, 40<<2, 0, 0x08, 0, 0xf0)},
+	}
+
+	for _, test := range tests {
+		if _, err := parseHeader(test.header); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
+
+func TestNES20ROMSize(t *testing.T) {
+	tests := []struct {
+		lsb, msb byte
+		unit     int
+		want     int
+	}{
+		{0x02, 0, PrgROMPageSize, 2 * PrgROMPageSize},
+		{0x34, 0xe, ChrROMPageSize, 0xe34 * ChrROMPageSize},
+		{0x00, 0xf, PrgROMPageSize, 1},
+		{7<<2 | 3, 0xf, PrgROMPageSize, 128 * 7},
+		{26 << 2, 0xf, PrgROMPageSize, maxROMSize},
+	}
+
+	for _, test := range tests {
+		size, err := nes20ROMSize(test.lsb, test.msb, test.unit)
+		if err != nil {
+			t.Errorf("$%x%02x: %v", test.msb, test.lsb, err)
+		} else if size != test.want {
+			t.Errorf("$%x%02x: size is %d, want %d", test.msb, test.lsb, size,
+				test.want)
+		}
+	}
+}
+
+func TestReadDump(t *testing.T) {
+	// A trainer, 1 PRG and 1 CHR page
+	header := testHeader(1, 1, 0x04)
+	romSize := TrainerSize + PrgROMPageSize + ChrROMPageSize
+	rom := make([]byte, romSize)
+	for i := range rom {
+		rom[i] = byte(i)
+	}
+
+	dump, err := ReadDump(bytes.NewReader(append(header, rom...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dump.Trainer, rom[:TrainerSize]) ||
+		!bytes.Equal(dump.PrgROM, rom[TrainerSize:romSize-ChrROMPageSize]) ||
+		!bytes.Equal(dump.ChrROM, rom[romSize-ChrROMPageSize:]) {
+		t.Error("Trainer, PRG or CHR ROM doesn't match the file's sections")
+	}
+
+	_, err = ReadDump(bytes.NewReader(append(header, rom[:romSize-1]...)))
+	if err == nil {
+		t.Error("No error reading a truncated ROM")
+	}
+
+	// A header whose size would overflow mustn't panic
+	_, err = ReadDump(bytes.NewReader(testHeader(0xff, 0xff, 0, 0x08, 0,
+		0xff)))
+	if err == nil {
+		t.Error("No error reading a ROM with a PRG ROM exponent of 63")
+	}
+}
@@ -1,6 +1,8 @@
 package ines
 
 import (
+	"sync"
+
 	"github.com/pkg/errors"
 )
 
@@ -12,28 +14,267 @@ const (
 	ChrRAMSize = 8192 // 8K, 0x2000
 )
 
+// Bank sizes used by mappers that switch banks smaller than a whole page
+const (
+	prgBankSize8k = 0x2000 // 8k
+	chrBankSize4k = 0x1000 // 4k
+	chrBankSize1k = 0x400  // 1k
+)
+
 type PrgROMPage [PrgROMPageSize]byte
 type ChrROMPage [ChrROMPageSize]byte
 
+// Mapper maps a cartridge's memory into the CPU and PPU address spaces.
+//
+// The mapper also maps the PPU's nametables, either to the PPU's internal VRAM
+// (CIRAM) or to the cartridge, in which case their reads and writes are served
+// by Read and Write.
+//
+// Mappers with an expansion sound chip additionally implement
+// apu.AudioExpansion, and are mixed with the APU's output when loaded.
 type Mapper interface {
 	Read(addr int) (byte, error)
 	Write(addr int, d byte) error
 	Observe(addr int) (byte, error)
 
+	Nametables() NametableMapping
+
 	Populate([]PrgROMPage, []ChrROMPage)
 	GetPRGRom() []PrgROMPage
 }
 
-func NewMapper(num int) (Mapper, error) {
-	m, ok := mappers[num]
+// IRQMapper is implemented by mappers that can generate interrupts. The
+// interrupt is level triggered, and IRQ returns whether it is asserted.
+type IRQMapper interface {
+	IRQ() bool
+}
+
+// PPUFetchObserver is implemented by mappers that watch the PPU's rendering
+// fetches from the pattern tables, such as MMC3 counting scanlines by the
+// rises of address line A12, or MMC2 switching CHR banks when specific tiles
+// are fetched.
+//
+// PPUFetch is called with the fetched address right after each fetch.
+type PPUFetchObserver interface {
+	PPUFetch(addr int)
+}
+
+// RenderingMapper is implemented by mappers that take part in the PPU's
+// rendering beyond serving its pattern table reads, such as the MMC5, which
+// substitutes nametable and attribute data, switches CHR banks per tile and
+// counts scanlines.
+type RenderingMapper interface {
+	// RenderScanline is called at the start of each scanline from 0 to 240,
+	// with the scanline's number while the PPU renders it, or -1 if rendering
+	// is disabled or the scanline isn't visible. tallSprites is set when the
+	// PPU renders 8x16 sprites.
+	RenderScanline(scanline int, tallSprites bool)
+
+	// FetchNametable is called for each of the background's fetches from the
+	// nametables and attribute tables, with addr in $2000-$2fff and col the
+	// fetched tile's column on screen (0 ~ 31). ok is false if the fetch
+	// should be served through the nametable mapping.
+	FetchNametable(addr int, col int) (d byte, ok bool)
+
+	// FetchPattern is called in place of Read for each of the rendering
+	// fetches from the pattern tables, with sprite set for sprite fetches.
+	FetchPattern(addr int, sprite bool) byte
+}
+
+// ClockedMapper is implemented by mappers that count CPU cycles, such as the
+// IRQ counters of the Konami VRCs, the FME-7 or the Namco 163.
+//
+// CPUCycle is called once per CPU cycle, after the PPU and APU are clocked.
+type ClockedMapper interface {
+	CPUCycle()
+}
+
+// ConsecutiveWriteMapper is implemented by mappers that ignore a write on the
+// cycle right after another write, such as the MMC1's serial port.
+//
+// Read-modify-write instructions write the unmodified value back before their
+// result, on consecutive cycles. WriteConsecutive is called in place of Write
+// for the result's write.
+type ConsecutiveWriteMapper interface {
+	WriteConsecutive(addr int, d byte) error
+}
+
+// BatteryMapper is implemented by mappers with memory that keeps its contents
+// while the console is off, such as battery backed PRG RAM or an EEPROM, which
+// games save their progress to.
+//
+// Battery returns a copy of the memory's contents, to be persisted only if the
+// ROM's header sets PersistentMemory. LoadBattery restores the contents from a
+// previous copy, ignoring data beyond the memory's size.
+type BatteryMapper interface {
+	Battery() []byte
+	LoadBattery(data []byte)
+}
+
+// TrainerMapper is implemented by mappers with PRG RAM, which a ROM's trainer
+// is loaded into.
+//
+// LoadTrainer copies the trainer to $7000-$71ff, returning false if the board
+// has no PRG RAM after all, as NES 2.0 headers can specify.
+type TrainerMapper interface {
+	LoadTrainer(trainer Trainer) bool
+}
+
+// MapperFactory creates a new instance of a mapper for a ROM, given the ROM's
+// header.
+type MapperFactory func(header INESHeader) Mapper
+
+var (
+	mappers   = map[int]MapperFactory{}
+	mappersMu sync.RWMutex
+)
+
+// RegisterMapper makes a mapper available to ROMs of the given iNES mapper
+// number, replacing the mapper previously registered for num.
+//
+// Each parsed ROM gets its own instance of the mapper from factory. Mappers
+// outside of this package can be registered to support custom or homebrew
+// boards.
+func RegisterMapper(num int, factory MapperFactory) {
+	if factory == nil {
+		panic("ines: RegisterMapper factory is nil")
+	}
+
+	mappersMu.Lock()
+	defer mappersMu.Unlock()
+
+	mappers[num] = factory
+}
+
+// MapperSupported returns whether a mapper is registered for the given iNES
+// mapper number.
+func MapperSupported(num int) bool {
+	mappersMu.RLock()
+	defer mappersMu.RUnlock()
+
+	_, ok := mappers[num]
+	return ok
+}
+
+// NewMapper creates a new instance of the mapper for a ROM with the given
+// header, or returns an error if its mapper number isn't registered.
+func NewMapper(header INESHeader) (Mapper, error) {
+	mappersMu.RLock()
+	factory, ok := mappers[header.MapperNumber]
+	mappersMu.RUnlock()
+
 	if !ok {
-		return nil, errors.Errorf("iNes Mapper %d not yet implemented", num)
+		return nil, errors.Errorf("iNes Mapper %d not yet implemented",
+			header.MapperNumber)
 	}
 
-	return m, nil
+	return factory(header), nil
 }
 
-var mappers = map[int]Mapper{
-	0: &Mapper000{},
-	1: &Mapper001{},
+func init() {
+	RegisterMapper(0, func(h INESHeader) Mapper {
+		return &Mapper000{
+			nametables: newNametables(h),
+			sRAM:       newPrgRAM(h, SRAMSize),
+		}
+	})
+	RegisterMapper(1, func(h INESHeader) Mapper {
+		return newMapper001(h)
+	})
+	RegisterMapper(2, func(h INESHeader) Mapper {
+		return &Mapper002{nametables: newNametables(h)}
+	})
+	RegisterMapper(3, func(h INESHeader) Mapper {
+		return &Mapper003{nametables: newNametables(h)}
+	})
+	RegisterMapper(4, func(h INESHeader) Mapper {
+		return &Mapper004{
+			nametables: newNametables(h),
+			sRAM:       newPrgRAM(h, SRAMSize),
+		}
+	})
+	RegisterMapper(5, func(h INESHeader) Mapper {
+		return &Mapper005{prgRAM: newPrgRAM(h, mmc5PrgRAMSize)}
+	})
+	RegisterMapper(7, func(h INESHeader) Mapper {
+		return &Mapper007{nametables: newNametables(h)}
+	})
+	RegisterMapper(9, func(h INESHeader) Mapper {
+		return &Mapper009{nametables: newNametables(h)}
+	})
+	RegisterMapper(10, func(h INESHeader) Mapper {
+		return &Mapper010{
+			nametables: newNametables(h),
+			sRAM:       newPrgRAM(h, SRAMSize),
+		}
+	})
+	RegisterMapper(11, func(h INESHeader) Mapper {
+		return &Mapper011{nametables: newNametables(h)}
+	})
+	RegisterMapper(16, func(h INESHeader) Mapper {
+		return &Mapper016{bandaiFCG{
+			nametables: newNametables(h),
+			eeprom:     newEEPROM(eeprom24C02Size, false),
+		}}
+	})
+	RegisterMapper(19, func(h INESHeader) Mapper {
+		return &Mapper019{sRAM: newPrgRAM(h, SRAMSize)}
+	})
+	RegisterMapper(21, func(h INESHeader) Mapper {
+		return &Mapper021{vrc24{
+			nametables: newNametables(h),
+			sRAM:       newPrgRAM(h, SRAMSize),
+		}}
+	})
+	RegisterMapper(22, func(h INESHeader) Mapper {
+		return &Mapper022{vrc24{
+			nametables: newNametables(h),
+			sRAM:       newPrgRAM(h, SRAMSize),
+		}}
+	})
+	RegisterMapper(23, func(h INESHeader) Mapper {
+		return &Mapper023{vrc24{
+			nametables: newNametables(h),
+			sRAM:       newPrgRAM(h, SRAMSize),
+		}}
+	})
+	RegisterMapper(24, func(h INESHeader) Mapper {
+		return &Mapper024{vrc6{
+			nametables: newNametables(h),
+			sRAM:       newPrgRAM(h, SRAMSize),
+		}}
+	})
+	RegisterMapper(25, func(h INESHeader) Mapper {
+		return &Mapper025{vrc24{
+			nametables: newNametables(h),
+			sRAM:       newPrgRAM(h, SRAMSize),
+		}}
+	})
+	RegisterMapper(26, func(h INESHeader) Mapper {
+		return &Mapper026{vrc6{
+			nametables: newNametables(h),
+			sRAM:       newPrgRAM(h, SRAMSize),
+		}}
+	})
+	RegisterMapper(66, func(h INESHeader) Mapper {
+		return &Mapper066{nametables: newNametables(h)}
+	})
+	RegisterMapper(69, func(h INESHeader) Mapper {
+		return &Mapper069{
+			nametables: newNametables(h),
+			sRAM:       newPrgRAM(h, SRAMSize),
+		}
+	})
+	RegisterMapper(85, func(h INESHeader) Mapper {
+		return &Mapper085{
+			nametables: newNametables(h),
+			sRAM:       newPrgRAM(h, SRAMSize),
+		}
+	})
+	RegisterMapper(159, func(h INESHeader) Mapper {
+		return &Mapper159{bandaiFCG{
+			nametables: newNametables(h),
+			eeprom:     newEEPROM(eeprom24C01Size, true),
+		}}
+	})
 }
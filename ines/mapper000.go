@@ -6,11 +6,13 @@ import (
 
 type Mapper000 struct {
 	prgROM []PrgROMPage
-	sRAM   [SRAMSize]byte
+	sRAM   prgRAM
 
 	chrROM    []ChrROMPage
 	chrRAM    [ChrRAMSize]byte
 	useChrRAM bool
+
+	nametables
 }
 
 func (m *Mapper000) Read(addr int) (d byte, err error) {
@@ -21,11 +23,14 @@ func (m *Mapper000) Read(addr int) (d byte, err error) {
 		}
 		return m.readChrROM(addr), nil
 
+	case addr < 0x3000:
+		return m.readNametable(addr), nil
+
 	case addr >= 0x8000:
 		return m.readPrgROM(addr - 0x8000), nil
 
 	case addr >= 0x6000:
-		return m.sRAM[addr-0x6000], nil
+		return m.sRAM.read(addr - 0x6000), nil
 	}
 
 	return 0, errors.Errorf("Invalid mapper reading addr %04x", addr)
@@ -36,8 +41,12 @@ func (m *Mapper000) Write(addr int, d byte) error {
 		m.chrRAM[addr] = d
 	}
 
+	if addr >= 0x2000 && addr < 0x3000 {
+		m.writeNametable(addr, d)
+	}
+
 	if addr >= 0x6000 && addr < 0x8000 {
-		m.sRAM[addr-0x6000] = d
+		m.sRAM.write(addr-0x6000, d)
 	}
 
 	return nil
@@ -69,6 +78,21 @@ func (m *Mapper000) GetPRGRom() []PrgROMPage {
 	return m.prgROM
 }
 
+// Battery returns a copy of PRG RAM.
+func (m *Mapper000) Battery() []byte {
+	return append([]byte(nil), m.sRAM...)
+}
+
+// LoadBattery restores PRG RAM from a previous copy.
+func (m *Mapper000) LoadBattery(data []byte) {
+	copy(m.sRAM, data)
+}
+
+// LoadTrainer copies a trainer to PRG RAM at $7000-$71ff.
+func (m *Mapper000) LoadTrainer(trainer Trainer) bool {
+	return m.sRAM.loadTrainer(trainer)
+}
+
 func (m *Mapper000) readPrgROM(addr int) byte {
 	return m.prgROM[addr/PrgROMPageSize][addr%PrgROMPageSize]
 }
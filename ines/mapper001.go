@@ -1,13 +1,66 @@
 package ines
 
+// mmc1Mirroring maps the mirroring bits of the MMC1's control register to
+// mirroring modes.
+var mmc1Mirroring = [4]int{
+	SingleScreenLowerMirroring,
+	SingleScreenUpperMirroring,
+	VerticalMirroring,
+	HorizontalMirroring,
+}
+
+// mmc1Board identifies the SxROM boards that use the CHR bank registers' high
+// bits for something other than switching CHR ROM, as they only have 8k of CHR
+// RAM.
+type mmc1Board int
+
+const (
+	// sxROMGeneric covers the boards that use the CHR bank registers for CHR
+	// ROM
+	sxROMGeneric mmc1Board = iota
+	// SNROM disables PRG RAM with bit 4
+	snROM
+	// SOROM switches between 2 8k PRG RAM banks with bit 3
+	soROM
+	// SUROM switches between 2 256k PRG ROM banks with bit 4
+	suROM
+	// SXROM switches PRG ROM like SUROM, and between 4 8k PRG RAM banks with
+	// bits 2-3
+	sxROM
+	// SEROM, SHROM and SH1ROM have 32k of PRG ROM, which isn't switched
+	seROM
+)
+
+// MMC1 NES 2.0 submappers that specify the board
+const (
+	mmc1SubmapperSUROM = 1
+	mmc1SubmapperSOROM = 2
+	mmc1SubmapperSXROM = 4
+	mmc1SubmapperSEROM = 5
+)
+
+// mmc1PrgRAMBanks is the largest amount of 8k PRG RAM banks on SxROM boards
+const mmc1PrgRAMBanks = 4
+
+// Mapper001 implements the MMC1 (SxROM boards).
+//
+// The MMC1 is configured through a serial port, shifting 5 bits into one of its
+// 4 registers. It switches 16k or 32k PRG ROM banks and 4k or 8k CHR banks,
+// and controls the nametable mirroring.
+//
+// The board is picked by the ROM's NES 2.0 submapper, or by its size, as boards
+// with 512k of PRG ROM or more than 8k of PRG RAM use the CHR bank registers to
+// extend the PRG banks.
 type Mapper001 struct {
 	prgROM []PrgROMPage
-	sRAM   [SRAMSize]byte
+	sRAM   prgRAM
 
 	chrROM    []ChrROMPage
 	chrRAM    [ChrRAMSize]byte
 	useChrRAM bool
 
+	board mmc1Board
+
 	sr         byte
 	writeCount int
 
@@ -16,24 +69,77 @@ type Mapper001 struct {
 	chr1 byte
 	prg  byte
 
-	booted bool
+	nametables
+}
+
+// newMapper001 creates an MMC1 for the board matching a ROM's header.
+func newMapper001(header INESHeader) *Mapper001 {
+	m := &Mapper001{
+		nametables: newNametables(header),
+
+		// PRG ROM's last bank is fixed at $c000-$ffff on power up
+		ctrl: 0x0c,
+	}
+
+	m.board = mmc1BoardOf(header)
+	m.sRAM = newPrgRAM(header, m.prgRAMBanks()*SRAMSize)
+
+	return m
+}
+
+// mmc1BoardOf returns the board of a ROM with an MMC1.
+func mmc1BoardOf(header INESHeader) mmc1Board {
+	if header.NES20 {
+		switch header.Submapper {
+		case mmc1SubmapperSUROM:
+			return suROM
+		case mmc1SubmapperSOROM:
+			return soROM
+		case mmc1SubmapperSXROM:
+			return sxROM
+		case mmc1SubmapperSEROM:
+			return seROM
+		}
+	}
+
+	prgRAMSize := header.PrgRAMSize + header.PrgNVRAMSize
+	switch {
+	case prgRAMSize > 2*SRAMSize:
+		return sxROM
+	case header.PrgROMSize > 16:
+		return suROM
+	case prgRAMSize == 2*SRAMSize:
+		return soROM
+	case header.ChrROMSize == 0:
+		return snROM
+	default:
+		return sxROMGeneric
+	}
 }
 
 func (m *Mapper001) Read(addr int) (d byte, err error) {
 	switch {
 	case addr < 0x2000:
+		index := m.decodeChrAddr(addr)
 		if m.useChrRAM {
-			return m.chrRAM[addr], nil
+			return m.chrRAM[index], nil
 		}
-		page, index := m.decodeChrROMAddr(addr)
-		return m.chrROM[page][index], nil
+		return m.chrROM[index/ChrROMPageSize][index%ChrROMPageSize], nil
+
+	case addr < 0x3000:
+		return m.readNametable(addr), nil
 
 	case addr >= 0x8000:
 		page, index := m.decodePrgROMAddr(addr - 0x8000)
 		return m.prgROM[page][index], nil
 
 	case addr >= 0x6000:
-		return m.sRAM[addr-0x6000], nil
+		index, ok := m.decodePrgRAMAddr(addr)
+		if !ok {
+			// Open bus
+			return 0, nil
+		}
+		return m.sRAM.read(index), nil
 
 	default:
 		return 0, nil
@@ -44,28 +150,34 @@ func (m *Mapper001) Read(addr int) (d byte, err error) {
 //
 // Writing to a mapper address is used for writing to RAM areas,
 // as well as writing to registers controlling the mapper.
-//
-// TODO: Ignore consecutive writes to the mapper, a cycle immediately the
-// the previous.
 func (m *Mapper001) Write(addr int, d byte) error {
 	if m.useChrRAM && addr < 0x2000 {
-		m.chrRAM[addr] = d
+		m.chrRAM[m.decodeChrAddr(addr)] = d
+	}
+
+	if addr >= 0x2000 && addr < 0x3000 {
+		m.writeNametable(addr, d)
 	}
 
 	if addr >= 0x6000 && addr < 0x8000 {
-		m.sRAM[addr-0x6000] = d
+		if index, ok := m.decodePrgRAMAddr(addr); ok {
+			m.sRAM.write(index, d)
+		}
 	}
 
 	if addr >= 0x8000 && addr < 0x10000 {
-		// Bit 7 of data is set, reset shift register
+		// Bit 7 of data is set, reset shift register and fix the last PRG
+		// ROM bank
 		if d&128 == 128 {
 			m.sr = 0
 			m.writeCount = 0
+			m.ctrl |= 0x0c
 		} else {
 			// First 5 writes to 0x8000 ~ 0xffff with bit 7 of data clear are
-			// shifted onto the internal shift register
-			m.sr = m.sr << 1
-			m.sr |= d & 1
+			// shifted onto the internal shift register, least significant bit
+			// first
+			m.sr >>= 1
+			m.sr |= (d & 1) << 4
 			m.writeCount++
 
 			// 5th write flushes the data to an internal register
@@ -81,8 +193,8 @@ func (m *Mapper001) Write(addr int, d byte) error {
 
 				switch regN {
 				case 0:
-					m.booted = true
 					m.ctrl = m.sr
+					m.setMirroring(mmc1Mirroring[m.ctrl&3])
 				case 1:
 					m.chr0 = m.sr
 				case 2:
@@ -127,57 +239,127 @@ func (m *Mapper001) GetPRGRom() []PrgROMPage {
 	return m.prgROM
 }
 
+// Battery returns a copy of PRG RAM.
+func (m *Mapper001) Battery() []byte {
+	return append([]byte(nil), m.sRAM...)
+}
+
+// LoadBattery restores PRG RAM from a previous copy.
+func (m *Mapper001) LoadBattery(data []byte) {
+	copy(m.sRAM, data)
+}
+
+// LoadTrainer copies a trainer to PRG RAM at $7000-$71ff.
+func (m *Mapper001) LoadTrainer(trainer Trainer) bool {
+	return m.sRAM.loadTrainer(trainer)
+}
+
+// WriteConsecutive handles a write on the cycle right after another write,
+// which the serial port ignores. Read-modify-write instructions are thus only
+// seen writing their unmodified value to the registers.
+func (m *Mapper001) WriteConsecutive(addr int, d byte) error {
+	if addr >= 0x8000 {
+		return nil
+	}
+	return m.Write(addr, d)
+}
+
+// decodePrgROMAddr returns the page and index in PRG ROM of an address
+// relative to $8000.
+//
+// Bits 2-3 of the control register select the PRG mode: switching 32k at
+// $8000 (0, 1), fixing the first bank at $8000 and switching $c000 (2), or
+// fixing the last bank at $c000 and switching $8000 (3). On SUROM and SXROM,
+// the banks are selected within the 256k half selected by bit 4 of CHR bank 0.
+// SEROM ignores the PRG bank register.
 func (m *Mapper001) decodePrgROMAddr(addr int) (page, index int) {
-	// Default to upper bank fixed
-	if !m.booted && addr > PrgROMPageSize {
-		return len(m.prgROM) - 1, addr % PrgROMPageSize
-	}
-
-	index = addr % PrgROMPageSize
-
-	// Test 32kb prg mode
-	if m.ctrl&2 == 0 {
-		page = (int(flip_reg(m.prg)) & 7) / 2
-		page += addr / PrgROMPageSize
-	} else {
-		// Test if the accessed bank is swappable
-		if (addr < PrgROMPageSize && m.ctrl&4 == 4) || (addr >= PrgROMPageSize && m.ctrl&4 == 0) {
-			page = int(flip_reg(m.prg)) & 15
-		} else if addr >= PrgROMPageSize {
-			// If upper bank is fixed, fix it to last page
-			page = len(m.prgROM) - 1
+	bank := int(m.prg & 0xf)
+	slot := addr / PrgROMPageSize
+
+	if m.board == seROM {
+		return slot % len(m.prgROM), addr % PrgROMPageSize
+	}
+
+	switch m.ctrl >> 2 & 3 {
+	case 0, 1:
+		page = bank&^1 + slot
+	case 2:
+		page = bank
+		if slot == 0 {
+			page = 0
+		}
+	case 3:
+		page = bank
+		if slot == 1 {
+			page = 0xf
 		}
 	}
 
-	return page, index
-}
+	if m.board == suROM || m.board == sxROM {
+		page |= int(m.chr0 & 0x10)
+	}
 
-func (m *Mapper001) decodeChrROMAddr(addr int) (page, index int) {
-	// Test 8kb chr mode
-	if m.ctrl&1 == 0 {
-		// Ignoring MSB of page num (bit 5) in 8kb mode
-		page = int(flip_reg(m.chr0)) & 0xf
-		index = addr % ChrROMPageSize
-	} else {
-		index = addr % (ChrROMPageSize / 2)
+	return page % len(m.prgROM), addr % PrgROMPageSize
+}
 
-		// Test for first or second chr 4k bank access
-		if addr < 0x1000 {
-			page = int(flip_reg(m.chr0))
-		} else {
-			page = int(flip_reg(m.chr1))
-		}
+// decodeChrAddr returns the offset in CHR memory of a PPU address in
+// $0000-$1fff.
+//
+// Bit 4 of the control register selects between switching a single 8k bank,
+// ignoring the low bit of CHR bank 0, or 2 4k banks.
+func (m *Mapper001) decodeChrAddr(addr int) int {
+	var bank int
+	switch {
+	case m.ctrl&0x10 == 0:
+		bank = int(m.chr0&0x1e) + addr/chrBankSize4k
+	case addr < 0x1000:
+		bank = int(m.chr0)
+	default:
+		bank = int(m.chr1)
+	}
 
-		index += (page % 2) * (ChrROMPageSize / 2)
-		page /= 2
+	banks := len(m.chrROM) * 2
+	if m.useChrRAM {
+		banks = ChrRAMSize / chrBankSize4k
 	}
 
-	return page, index
+	return bank%banks*chrBankSize4k + addr%chrBankSize4k
 }
 
-func flip_reg(d byte) byte {
-	d = ((d >> 1) & 0x55) | ((d & 0x55) << 1)
-	d = ((d >> 2) & 0x33) | ((d & 0x33) << 2)
-	d = ((d >> 4) & 0x0F) | ((d & 0x0F) << 4)
-	return d >> 3
+// prgRAMBanks returns the amount of 8k PRG RAM banks on the board, when not
+// specified by an NES 2.0 header.
+func (m *Mapper001) prgRAMBanks() int {
+	switch m.board {
+	case soROM:
+		return 2
+	case sxROM:
+		return mmc1PrgRAMBanks
+	default:
+		return 1
+	}
+}
+
+// decodePrgRAMAddr returns the offset in PRG RAM of a CPU address in
+// $6000-$7fff, or false if PRG RAM is disabled.
+//
+// PRG RAM is disabled by bit 4 of the PRG bank register, and on SNROM by bit 4
+// of CHR bank 0. SOROM and SXROM switch 8k PRG RAM banks by CHR bank 0.
+func (m *Mapper001) decodePrgRAMAddr(addr int) (index int, ok bool) {
+	if m.prg&0x10 != 0 {
+		return 0, false
+	}
+
+	var bank int
+	switch m.board {
+	case snROM:
+		if m.chr0&0x10 != 0 {
+			return 0, false
+		}
+	case soROM:
+		bank = int(m.chr0 >> 3 & 1)
+	case sxROM:
+		bank = int(m.chr0 >> 2 & 3)
+	}
+
+	return bank*SRAMSize + addr - 0x6000, true
 }
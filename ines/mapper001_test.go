@@ -0,0 +1,215 @@
+package ines
+
+import (
+	"testing"
+)
+
+// newTestMMC1 creates an MMC1 for header, populated with a PRG ROM from
+// newTestPrgROM and empty CHR ROM pages.
+func newTestMMC1(header INESHeader) *Mapper001 {
+	header.MapperNumber = 1
+
+	m, err := NewMapper(header)
+	if err != nil {
+		panic(err)
+	}
+	m.Populate(newTestPrgROM(header.PrgROMSize),
+		make([]ChrROMPage, header.ChrROMSize))
+
+	return m.(*Mapper001)
+}
+
+// writeMMC1 writes a 5 bit value to the register at addr through the serial
+// port, least significant bit first.
+func writeMMC1(m Mapper, addr int, v byte) {
+	for i := uint(0); i < 5; i++ {
+		m.Write(addr, v>>i&1)
+	}
+}
+
+func TestMMC1BoardOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		header INESHeader
+		want   mmc1Board
+	}{
+		{"CHR ROM", INESHeader{PrgROMSize: 16, ChrROMSize: 16,
+			PrgRAMSize: SRAMSize}, sxROMGeneric},
+		{"CHR RAM", INESHeader{PrgROMSize: 16, PrgRAMSize: SRAMSize},
+			snROM},
+		{"512k PRG ROM", INESHeader{PrgROMSize: 32, PrgRAMSize: SRAMSize},
+			suROM},
+		{"16k PRG RAM", INESHeader{PrgROMSize: 16, PrgRAMSize: 2 * SRAMSize},
+			soROM},
+		{"32k PRG RAM", INESHeader{PrgROMSize: 32, PrgRAMSize: 4 * SRAMSize},
+			sxROM},
+		{"NES 2.0 PRG NVRAM", INESHeader{NES20: true, PrgROMSize: 16,
+			PrgRAMSize: SRAMSize, PrgNVRAMSize: SRAMSize}, soROM},
+		{"NES 2.0 submapper 0", INESHeader{NES20: true, PrgROMSize: 16},
+			snROM},
+		{"NES 2.0 SUROM", INESHeader{NES20: true, Submapper: 1,
+			PrgROMSize: 16}, suROM},
+		{"NES 2.0 SOROM", INESHeader{NES20: true, Submapper: 2,
+			PrgROMSize: 16}, soROM},
+		{"NES 2.0 SXROM", INESHeader{NES20: true, Submapper: 4,
+			PrgROMSize: 16}, sxROM},
+		{"NES 2.0 SEROM", INESHeader{NES20: true, Submapper: 5,
+			PrgROMSize: 2}, seROM},
+	}
+
+	for _, test := range tests {
+		if board := mmc1BoardOf(test.header); board != test.want {
+			t.Errorf("%s: board is %d, want %d", test.name, board, test.want)
+		}
+	}
+}
+
+func TestMMC1OuterPrgBank(t *testing.T) {
+	m := newTestMMC1(INESHeader{PrgROMSize: 32, PrgRAMSize: SRAMSize})
+
+	tests := []struct {
+		chr0 byte
+		prg  byte
+		// The 8k banks at $8000 and $c000, in the default PRG mode fixing the
+		// last bank of the 256k half at $c000
+		want [2]byte
+	}{
+		{0x00, 2, [2]byte{4, 30}},
+		{0x10, 2, [2]byte{36, 62}},
+		{0x10, 15, [2]byte{62, 62}},
+		{0x0f, 7, [2]byte{14, 30}},
+	}
+
+	for _, test := range tests {
+		writeMMC1(m, 0xa000, test.chr0)
+		writeMMC1(m, 0xe000, test.prg)
+
+		for i, addr := range []int{0x8000, 0xc000} {
+			if d, _ := m.Read(addr); d != test.want[i] {
+				t.Errorf("CHR bank 0 $%02x, PRG bank %d: $%04x reads bank "+
+					"%d, want %d", test.chr0, test.prg, addr, d, test.want[i])
+			}
+		}
+	}
+
+	// The high bit of the PRG bank register doesn't switch PRG ROM
+	writeMMC1(m, 0xa000, 0)
+	writeMMC1(m, 0xe000, 0x12)
+	if d, _ := m.Read(0x8000); d != 4 {
+		t.Errorf("PRG bank $12: $8000 reads bank %d, want 4", d)
+	}
+}
+
+func TestMMC1PrgRAMBanks(t *testing.T) {
+	tests := []struct {
+		name   string
+		header INESHeader
+		// chr0 holds the CHR bank 0 values selecting each PRG RAM bank
+		chr0 []byte
+	}{
+		{"SOROM", INESHeader{PrgROMSize: 16, PrgRAMSize: 2 * SRAMSize},
+			[]byte{0x00, 0x08}},
+		{"SXROM", INESHeader{PrgROMSize: 32, PrgRAMSize: 4 * SRAMSize},
+			[]byte{0x00, 0x04, 0x08, 0x0c}},
+	}
+
+	for _, test := range tests {
+		m := newTestMMC1(test.header)
+
+		for bank, chr0 := range test.chr0 {
+			writeMMC1(m, 0xa000, chr0)
+			m.Write(0x6000, byte(0x10+bank))
+			m.Write(0x7fff, byte(0x20+bank))
+		}
+
+		for bank, chr0 := range test.chr0 {
+			writeMMC1(m, 0xa000, chr0)
+			if d, _ := m.Read(0x6000); d != byte(0x10+bank) {
+				t.Errorf("%s: bank %d reads $%02x at $6000, want $%02x",
+					test.name, bank, d, 0x10+bank)
+			}
+			if d, _ := m.Read(0x7fff); d != byte(0x20+bank) {
+				t.Errorf("%s: bank %d reads $%02x at $7fff, want $%02x",
+					test.name, bank, d, 0x20+bank)
+			}
+		}
+
+		if battery := m.Battery(); len(battery) != len(test.chr0)*SRAMSize {
+			t.Errorf("%s: %d bytes of PRG RAM, want %d", test.name,
+				len(battery), len(test.chr0)*SRAMSize)
+		}
+	}
+}
+
+func TestMMC1PrgRAMDisable(t *testing.T) {
+	tests := []struct {
+		name   string
+		header INESHeader
+		addr   int
+		v      byte
+	}{
+		{"PRG bank bit 4", INESHeader{PrgROMSize: 16, ChrROMSize: 16,
+			PrgRAMSize: SRAMSize}, 0xe000, 0x10},
+		{"SNROM CHR bank 0 bit 4", INESHeader{PrgROMSize: 16,
+			PrgRAMSize: SRAMSize}, 0xa000, 0x10},
+	}
+
+	for _, test := range tests {
+		m := newTestMMC1(test.header)
+		m.Write(0x6000, 0x55)
+
+		writeMMC1(m, test.addr, test.v)
+		m.Write(0x6000, 0xaa)
+		if d, _ := m.Read(0x6000); d != 0 {
+			t.Errorf("%s: disabled PRG RAM reads $%02x, want open bus",
+				test.name, d)
+		}
+
+		writeMMC1(m, test.addr, 0)
+		if d, _ := m.Read(0x6000); d != 0x55 {
+			t.Errorf("%s: reenabled PRG RAM reads $%02x, want $55",
+				test.name, d)
+		}
+	}
+
+	// CHR bank 0 bit 4 only disables PRG RAM on SNROM
+	m := newTestMMC1(INESHeader{PrgROMSize: 16, ChrROMSize: 16,
+		PrgRAMSize: SRAMSize})
+	writeMMC1(m, 0xa000, 0x10)
+	m.Write(0x6000, 0x55)
+	if d, _ := m.Read(0x6000); d != 0x55 {
+		t.Errorf("PRG RAM reads $%02x with CHR ROM, want $55", d)
+	}
+}
+
+func TestMMC1ConsecutiveWrites(t *testing.T) {
+	m := newTestMMC1(INESHeader{PrgROMSize: 16, ChrROMSize: 16,
+		PrgRAMSize: SRAMSize})
+
+	// Read-modify-write instructions on $e000, writing the unmodified value
+	// and then a result with its low bit flipped. Only the first write of
+	// each is shifted into the serial port.
+	prg := byte(0x05)
+	for i := uint(0); i < 5; i++ {
+		orig := prg >> i & 1
+		m.Write(0xe000, orig)
+		m.WriteConsecutive(0xe000, orig^1)
+	}
+	if m.prg != prg {
+		t.Errorf("PRG bank register is $%02x, want $%02x", m.prg, prg)
+	}
+
+	// A consecutive write resetting the shift register is ignored as well
+	m.Write(0xe000, 1)
+	m.WriteConsecutive(0xe000, 0x80)
+	if m.writeCount != 1 {
+		t.Errorf("Shift register holds %d bits, want 1", m.writeCount)
+	}
+
+	// Consecutive writes to PRG RAM aren't ignored
+	m.Write(0x6000, 0x10)
+	m.WriteConsecutive(0x6000, 0x11)
+	if d, _ := m.Read(0x6000); d != 0x11 {
+		t.Errorf("PRG RAM reads $%02x, want the consecutive write's $11", d)
+	}
+}
@@ -0,0 +1,78 @@
+package ines
+
+// Mapper002 implements UxROM boards.
+//
+// UxROM switches a 16k PRG ROM bank at $8000-$bfff, with the last bank fixed at
+// $c000-$ffff. The board uses 8k of unbanked CHR RAM.
+type Mapper002 struct {
+	prgROM []PrgROMPage
+
+	chrROM    []ChrROMPage
+	chrRAM    [ChrRAMSize]byte
+	useChrRAM bool
+
+	bank int
+
+	nametables
+}
+
+func (m *Mapper002) Read(addr int) (d byte, err error) {
+	switch {
+	case addr < 0x2000:
+		if m.useChrRAM {
+			return m.chrRAM[addr], nil
+		}
+		return m.chrROM[0][addr], nil
+
+	case addr < 0x3000:
+		return m.readNametable(addr), nil
+
+	case addr >= 0xc000:
+		return m.prgROM[len(m.prgROM)-1][addr-0xc000], nil
+
+	case addr >= 0x8000:
+		return m.prgROM[m.bank][addr-0x8000], nil
+
+	default:
+		return 0, nil
+	}
+}
+
+// Write handles writing to an address mapped by the mapper.
+//
+// Writing to $8000-$ffff selects the PRG ROM bank. The board has bus
+// conflicts, so the value written is ANDed with the ROM's value at addr.
+func (m *Mapper002) Write(addr int, d byte) error {
+	if m.useChrRAM && addr < 0x2000 {
+		m.chrRAM[addr] = d
+	}
+
+	if addr >= 0x2000 && addr < 0x3000 {
+		m.writeNametable(addr, d)
+	}
+
+	if addr >= 0x8000 {
+		rom, _ := m.Read(addr)
+		m.bank = int(d&rom) % len(m.prgROM)
+	}
+
+	return nil
+}
+
+func (m *Mapper002) Observe(addr int) (d byte, err error) {
+	// There is no side effect to reading from mapper 002
+	return m.Read(addr)
+}
+
+func (m *Mapper002) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	if len(chrROM) == 0 {
+		m.useChrRAM = true
+	}
+
+	m.prgROM = prgROM
+	m.chrROM = chrROM
+}
+
+func (m *Mapper002) GetPRGRom() []PrgROMPage {
+	return m.prgROM
+}
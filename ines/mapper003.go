@@ -0,0 +1,84 @@
+package ines
+
+// Mapper003 implements CNROM boards.
+//
+// CNROM has 16k or 32k of unbanked PRG ROM, and switches 8k CHR ROM banks.
+// ROMs without CHR ROM get 8k of unbanked CHR RAM instead.
+type Mapper003 struct {
+	prgROM []PrgROMPage
+
+	chrROM    []ChrROMPage
+	chrRAM    [ChrRAMSize]byte
+	useChrRAM bool
+
+	bank int
+
+	nametables
+}
+
+func (m *Mapper003) Read(addr int) (d byte, err error) {
+	switch {
+	case addr < 0x2000:
+		if m.useChrRAM {
+			return m.chrRAM[addr], nil
+		}
+		return m.chrROM[m.bank][addr], nil
+
+	case addr < 0x3000:
+		return m.readNametable(addr), nil
+
+	case addr >= 0x8000:
+		addr -= 0x8000
+		return m.prgROM[addr/PrgROMPageSize][addr%PrgROMPageSize], nil
+
+	default:
+		return 0, nil
+	}
+}
+
+// Write handles writing to an address mapped by the mapper.
+//
+// Writing to $8000-$ffff selects the CHR ROM bank. The board has bus
+// conflicts, so the value written is ANDed with the ROM's value at addr.
+func (m *Mapper003) Write(addr int, d byte) error {
+	if m.useChrRAM && addr < 0x2000 {
+		m.chrRAM[addr] = d
+	}
+
+	if addr >= 0x2000 && addr < 0x3000 {
+		m.writeNametable(addr, d)
+	}
+
+	if addr >= 0x8000 && !m.useChrRAM {
+		rom, _ := m.Read(addr)
+		m.bank = int(d&rom) % len(m.chrROM)
+	}
+
+	return nil
+}
+
+func (m *Mapper003) Observe(addr int) (d byte, err error) {
+	// There is no side effect to reading from mapper 003
+	return m.Read(addr)
+}
+
+func (m *Mapper003) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	// If only one page of prg rom, duplicate it
+	if len(prgROM) == 1 {
+		var pageCopy PrgROMPage
+		copy(pageCopy[:], prgROM[0][:])
+
+		prgROM = append(prgROM, pageCopy)
+	}
+
+	if len(chrROM) == 0 {
+		m.useChrRAM = true
+	}
+
+	m.prgROM = prgROM
+	m.chrROM = chrROM
+}
+
+func (m *Mapper003) GetPRGRom() []PrgROMPage {
+	return m.prgROM
+}
@@ -0,0 +1,248 @@
+package ines
+
+// Mapper004 implements the MMC3 (TxROM boards).
+//
+// The MMC3 has 8 bank registers (R0 ~ R7), switching 8k PRG ROM banks and 1k
+// or 2k CHR banks, and controls the nametable mirroring.
+//
+// It also contains a scanline counter, clocked by rises of the PPU's address
+// line A12 that usually happen once per scanline, when the PPU moves from
+// fetching the background's tiles to fetching the sprites' tiles. The counter
+// generates an IRQ when it reaches 0.
+type Mapper004 struct {
+	prgROM []PrgROMPage
+	sRAM   prgRAM
+
+	chrROM    []ChrROMPage
+	chrRAM    [ChrRAMSize]byte
+	useChrRAM bool
+
+	prgBanks int
+	chrBanks int
+
+	// bankSelect selects the bank register to update and the banking modes
+	bankSelect byte
+	regs       [8]int
+
+	nametables
+
+	sRAMEnabled   bool
+	sRAMProtected bool
+
+	// Scanline counter
+	irqLatch   byte
+	irqCounter byte
+	irqReload  bool
+	irqEnabled bool
+	irq        bool
+
+	a12 bool
+}
+
+func (m *Mapper004) Read(addr int) (d byte, err error) {
+	switch {
+	case addr < 0x2000:
+		if m.useChrRAM {
+			return m.chrRAM[m.decodeChrAddr(addr)], nil
+		}
+		index := m.decodeChrAddr(addr)
+		return m.chrROM[index/ChrROMPageSize][index%ChrROMPageSize], nil
+
+	case addr < 0x3000:
+		return m.readNametable(addr), nil
+
+	case addr >= 0x8000:
+		index := m.decodePrgROMAddr(addr)
+		return m.prgROM[index/PrgROMPageSize][index%PrgROMPageSize], nil
+
+	case addr >= 0x6000:
+		if !m.sRAMEnabled {
+			// Open bus
+			return 0, nil
+		}
+		return m.sRAM.read(addr - 0x6000), nil
+
+	default:
+		return 0, nil
+	}
+}
+
+// Write handles writing to an address mapped by the mapper.
+//
+// The MMC3's registers are mapped to $8000-$ffff in pairs, selected by the
+// address' range and whether it is even or odd.
+func (m *Mapper004) Write(addr int, d byte) error {
+	switch {
+	case addr < 0x2000:
+		if m.useChrRAM {
+			m.chrRAM[m.decodeChrAddr(addr)] = d
+		}
+
+	case addr < 0x3000:
+		m.writeNametable(addr, d)
+
+	case addr >= 0x6000 && addr < 0x8000:
+		if m.sRAMEnabled && !m.sRAMProtected {
+			m.sRAM.write(addr-0x6000, d)
+		}
+
+	case addr >= 0x8000 && addr < 0xa000:
+		if addr%2 == 0 {
+			m.bankSelect = d
+		} else {
+			m.regs[m.bankSelect&7] = int(d)
+		}
+
+	case addr >= 0xa000 && addr < 0xc000:
+		if addr%2 == 0 {
+			mirroring := VerticalMirroring
+			if d&1 == 1 {
+				mirroring = HorizontalMirroring
+			}
+			m.setMirroring(mirroring)
+		} else {
+			m.sRAMEnabled = d>>7 == 1
+			m.sRAMProtected = d>>6&1 == 1
+		}
+
+	case addr >= 0xc000 && addr < 0xe000:
+		if addr%2 == 0 {
+			m.irqLatch = d
+		} else {
+			// The counter is reloaded on the next clock
+			m.irqCounter = 0
+			m.irqReload = true
+		}
+
+	case addr >= 0xe000:
+		if addr%2 == 0 {
+			// Disabling IRQs also acknowledges a pending one
+			m.irqEnabled = false
+			m.irq = false
+		} else {
+			m.irqEnabled = true
+		}
+	}
+
+	return nil
+}
+
+func (m *Mapper004) Observe(addr int) (d byte, err error) {
+	// There is no side effect to reading from mapper 004
+	return m.Read(addr)
+}
+
+func (m *Mapper004) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	if len(chrROM) == 0 {
+		m.useChrRAM = true
+	}
+
+	m.prgROM = prgROM
+	m.chrROM = chrROM
+
+	m.prgBanks = len(prgROM) * PrgROMPageSize / prgBankSize8k
+	m.chrBanks = len(chrROM) * ChrROMPageSize / chrBankSize1k
+	if m.useChrRAM {
+		m.chrBanks = ChrRAMSize / chrBankSize1k
+	}
+
+	m.sRAMEnabled = true
+}
+
+func (m *Mapper004) GetPRGRom() []PrgROMPage {
+	return m.prgROM
+}
+
+// Battery returns a copy of PRG RAM.
+func (m *Mapper004) Battery() []byte {
+	return append([]byte(nil), m.sRAM...)
+}
+
+// LoadBattery restores PRG RAM from a previous copy.
+func (m *Mapper004) LoadBattery(data []byte) {
+	copy(m.sRAM, data)
+}
+
+// LoadTrainer copies a trainer to PRG RAM at $7000-$71ff.
+func (m *Mapper004) LoadTrainer(trainer Trainer) bool {
+	return m.sRAM.loadTrainer(trainer)
+}
+
+// IRQ returns whether the scanline counter is asserting an interrupt.
+func (m *Mapper004) IRQ() bool {
+	return m.irq
+}
+
+// PPUFetch watches the PPU's pattern table fetches, clocking the scanline
+// counter on rises of A12.
+func (m *Mapper004) PPUFetch(addr int) {
+	a12 := addr&0x1000 != 0
+	if a12 && !m.a12 {
+		m.clockCounter()
+	}
+	m.a12 = a12
+}
+
+// clockCounter clocks the scanline counter, reloading it when it reaches 0 and
+// generating an interrupt if enabled.
+func (m *Mapper004) clockCounter() {
+	if m.irqCounter == 0 || m.irqReload {
+		m.irqCounter = m.irqLatch
+		m.irqReload = false
+	} else {
+		m.irqCounter--
+	}
+
+	if m.irqCounter == 0 && m.irqEnabled {
+		m.irq = true
+	}
+}
+
+// decodePrgROMAddr returns the offset in PRG ROM of a CPU address in
+// $8000-$ffff.
+//
+// $a000-$bfff is always switched by R7 and $e000-$ffff is fixed to the last
+// bank. Bit 6 of bank select swaps between R6 and the second to last bank in
+// $8000-$9fff and $c000-$dfff.
+func (m *Mapper004) decodePrgROMAddr(addr int) int {
+	var bank int
+
+	slot := (addr - 0x8000) / prgBankSize8k
+	if m.bankSelect&0x40 != 0 && slot%2 == 0 {
+		slot ^= 2
+	}
+
+	switch slot {
+	case 0:
+		bank = m.regs[6]
+	case 1:
+		bank = m.regs[7]
+	case 2:
+		bank = m.prgBanks - 2
+	case 3:
+		bank = m.prgBanks - 1
+	}
+
+	return (bank%m.prgBanks)*prgBankSize8k + addr%prgBankSize8k
+}
+
+// decodeChrAddr returns the offset in CHR memory of a PPU address in
+// $0000-$1fff.
+//
+// R0 and R1 switch 2k banks in $0000-$0fff, and R2 ~ R5 switch 1k banks in
+// $1000-$1fff. Bit 7 of bank select swaps between the two halves.
+func (m *Mapper004) decodeChrAddr(addr int) int {
+	if m.bankSelect&0x80 != 0 {
+		addr ^= 0x1000
+	}
+
+	var bank int
+	if addr < 0x1000 {
+		// 2k banks ignore the low bit of the bank number
+		bank = m.regs[addr/0x800]&0xfe + addr/chrBankSize1k%2
+	} else {
+		bank = m.regs[2+(addr-0x1000)/chrBankSize1k]
+	}
+
+	return (bank%m.chrBanks)*chrBankSize1k + addr%chrBankSize1k
+}
@@ -0,0 +1,44 @@
+package ines
+
+import (
+	"testing"
+)
+
+func TestMapper004PrgBanks(t *testing.T) {
+	tests := []struct {
+		bankSelect byte
+		addr       int
+		bank       byte
+	}{
+		// PRG mode 0, R6 at $8000 and the second to last bank at $c000
+		{0x06, 0x8000, 2},
+		{0x06, 0xa000, 5},
+		{0x06, 0xc000, 14},
+		{0x06, 0xe000, 15},
+
+		// PRG mode 1 swaps $8000 and $c000, $a000 and $e000 are unaffected
+		{0x46, 0x8000, 14},
+		{0x46, 0xa000, 5},
+		{0x46, 0xc000, 2},
+		{0x46, 0xe000, 15},
+	}
+
+	for _, test := range tests {
+		m := newTestMapper(4, 8, 1)
+
+		// R6 = 2, R7 = 5
+		m.Write(0x8000, 6)
+		m.Write(0x8001, 2)
+		m.Write(0x8000, 7)
+		m.Write(0x8001, 5)
+
+		m.Write(0x8000, test.bankSelect)
+
+		// The bank's last byte, as the vectors in $fffa-$ffff
+		addr := test.addr + prgBankSize8k - 1
+		if d, _ := m.Read(addr); d != test.bank {
+			t.Errorf("Bank select $%02x: $%04x reads bank %d, want %d",
+				test.bankSelect, addr, d, test.bank)
+		}
+	}
+}
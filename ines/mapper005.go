@@ -0,0 +1,495 @@
+package ines
+
+const (
+	mmc5ExRAMSize = 0x400 // 1k
+	// mmc5PrgRAMSize is the largest amount of PRG RAM on ExROM boards, 8 8k
+	// banks
+	mmc5PrgRAMSize = 0x10000 // 64k
+)
+
+// Nametable sources of the MMC5, selected for each nametable by $5105
+const (
+	mmc5CIRAMLower = iota
+	mmc5CIRAMUpper
+	mmc5ExRAMNametable
+	mmc5FillNametable
+)
+
+// ExRAM modes of the MMC5, set by $5104
+const (
+	exRAMNametable = iota
+	exRAMExtendedAttributes
+	exRAMReadWrite
+	exRAMReadOnly
+)
+
+// Mapper005 implements the MMC5 (ExROM boards), used by Castlevania III and
+// many of Koei's games.
+//
+// The MMC5 switches PRG ROM and PRG RAM in 8k to 32k banks, and CHR in 1k to 8k
+// banks, with a separate set of CHR banks for the background when the PPU
+// renders 8x16 sprites. Its 1k of ExRAM is used as an extra nametable, to hold
+// a CHR bank and palette for each background tile (extended attributes), or as
+// plain RAM. It can also fill nametables with a single tile, display a
+// vertical split from ExRAM, count scanlines and multiply numbers.
+//
+// The MMC5's expansion sound isn't emulated.
+type Mapper005 struct {
+	prgROM []PrgROMPage
+	prgRAM prgRAM
+
+	chrROM    []ChrROMPage
+	chrRAM    [ChrRAMSize]byte
+	useChrRAM bool
+
+	prgBanks int
+	chrSize  int
+
+	prgMode byte
+	// prgRegs holds the PRG bank registers $5113-$5117
+	prgRegs       [5]byte
+	prgRAMProtect [2]byte
+
+	chrMode byte
+	// chrRegs holds the CHR bank registers $5120-$512b. The first 8 are used
+	// for sprites, and the last 4 for the background when the PPU renders 8x16
+	// sprites.
+	chrRegs  [12]int
+	chrUpper int
+	// lastChrBg is set when the background CHR banks were the last written,
+	// which the PPU's reads through PPUDATA use with 8x16 sprites
+	lastChrBg bool
+
+	exRAM     [mmc5ExRAMSize]byte
+	exRAMMode byte
+
+	ntSources byte
+	fillTile  byte
+	fillAttr  byte
+
+	// Vertical split
+	splitEnabled bool
+	splitRight   bool
+	splitTile    int
+	splitScroll  int
+	splitBank    int
+
+	multiplicand byte
+	multiplier   byte
+
+	// Scanline counter
+	irqCompare int
+	irqEnabled bool
+	irqPending bool
+	inFrame    bool
+	counter    int
+
+	// Rendering state
+	scanline    int
+	tallSprites bool
+
+	// tileSplit and tileExAttr describe the background tile last fetched from
+	// the nametables, whether it is part of the split, and its extended
+	// attributes
+	tileSplit   bool
+	tileExAttr  bool
+	tileExAttrs byte
+}
+
+func (m *Mapper005) Read(addr int) (d byte, err error) {
+	d = m.read(addr)
+	if addr == 0x5204 {
+		// Reading the IRQ status acknowledges a pending interrupt
+		m.irqPending = false
+	}
+
+	return d, nil
+}
+
+func (m *Mapper005) read(addr int) byte {
+	switch {
+	case addr < 0x2000:
+		// Reads through PPUDATA use the last written set of CHR banks
+		return m.readChr(m.decodeChrAddr(addr, m.tallSprites && m.lastChrBg))
+
+	case addr < 0x3000:
+		return m.readNametable(addr)
+
+	case addr == 0x5204:
+		var d byte
+		if m.irqPending {
+			d |= 0x80
+		}
+		if m.inFrame {
+			d |= 0x40
+		}
+		return d
+
+	case addr == 0x5205:
+		return byte(uint16(m.multiplicand) * uint16(m.multiplier))
+
+	case addr == 0x5206:
+		return byte(uint16(m.multiplicand) * uint16(m.multiplier) >> 8)
+
+	case addr >= 0x5c00 && addr < 0x6000:
+		if m.exRAMMode < exRAMReadWrite {
+			// Open bus
+			return 0
+		}
+		return m.exRAM[addr-0x5c00]
+
+	case addr >= 0x6000:
+		bank, ram := m.prgBank(addr)
+		if ram {
+			return m.prgRAM.read(bank*prgBankSize8k + addr%prgBankSize8k)
+		}
+
+		index := bank*prgBankSize8k + addr%prgBankSize8k
+		return m.prgROM[index/PrgROMPageSize][index%PrgROMPageSize]
+
+	default:
+		return 0
+	}
+}
+
+// Write handles writing to an address mapped by the mapper.
+//
+// The MMC5's registers are mapped to $5100-$5206, and its ExRAM to
+// $5c00-$5fff.
+func (m *Mapper005) Write(addr int, d byte) error {
+	switch {
+	case addr < 0x2000:
+		if m.useChrRAM {
+			m.chrRAM[m.decodeChrAddr(addr, false)] = d
+		}
+
+	case addr < 0x3000:
+		// Only ExRAM is writable, fill mode ignores writes
+		if m.ntSource(addr) == mmc5ExRAMNametable &&
+			m.exRAMMode <= exRAMExtendedAttributes {
+
+			m.exRAM[addr%mmc5ExRAMSize] = d
+		}
+
+	case addr == 0x5100:
+		m.prgMode = d & 3
+	case addr == 0x5101:
+		m.chrMode = d & 3
+	case addr == 0x5102, addr == 0x5103:
+		m.prgRAMProtect[addr-0x5102] = d & 3
+	case addr == 0x5104:
+		m.exRAMMode = d & 3
+	case addr == 0x5105:
+		m.ntSources = d
+	case addr == 0x5106:
+		m.fillTile = d
+	case addr == 0x5107:
+		m.fillAttr = d & 3
+
+	case addr >= 0x5113 && addr <= 0x5117:
+		m.prgRegs[addr-0x5113] = d
+
+	case addr >= 0x5120 && addr <= 0x512b:
+		m.chrRegs[addr-0x5120] = int(d) | m.chrUpper<<8
+		m.lastChrBg = addr >= 0x5128
+	case addr == 0x5130:
+		m.chrUpper = int(d & 3)
+
+	case addr == 0x5200:
+		m.splitEnabled = d>>7 == 1
+		m.splitRight = d>>6&1 == 1
+		m.splitTile = int(d & 0x1f)
+	case addr == 0x5201:
+		m.splitScroll = int(d)
+	case addr == 0x5202:
+		m.splitBank = int(d)
+
+	case addr == 0x5203:
+		m.irqCompare = int(d)
+	case addr == 0x5204:
+		m.irqEnabled = d>>7 == 1
+
+	case addr == 0x5205:
+		m.multiplicand = d
+	case addr == 0x5206:
+		m.multiplier = d
+
+	case addr >= 0x5c00 && addr < 0x6000:
+		switch m.exRAMMode {
+		case exRAMNametable, exRAMExtendedAttributes:
+			// ExRAM is only writable while the PPU renders in the modes
+			// it is used for rendering
+			if !m.inFrame {
+				d = 0
+			}
+			m.exRAM[addr-0x5c00] = d
+		case exRAMReadWrite:
+			m.exRAM[addr-0x5c00] = d
+		}
+
+	case addr >= 0x6000:
+		bank, ram := m.prgBank(addr)
+		if ram && m.prgRAMProtect == [2]byte{2, 1} {
+			m.prgRAM.write(bank*prgBankSize8k+addr%prgBankSize8k, d)
+		}
+	}
+
+	return nil
+}
+
+func (m *Mapper005) Observe(addr int) (d byte, err error) {
+	// Observing doesn't acknowledge interrupts
+	return m.read(addr), nil
+}
+
+func (m *Mapper005) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	if len(chrROM) == 0 {
+		m.useChrRAM = true
+	}
+
+	m.prgROM = prgROM
+	m.chrROM = chrROM
+
+	m.prgBanks = len(prgROM) * PrgROMPageSize / prgBankSize8k
+	m.chrSize = len(chrROM) * ChrROMPageSize
+	if m.useChrRAM {
+		m.chrSize = ChrRAMSize
+	}
+
+	// The last bank is mapped to $e000-$ffff on power up
+	m.prgMode = 3
+	m.prgRegs[4] = 0xff
+}
+
+func (m *Mapper005) GetPRGRom() []PrgROMPage {
+	return m.prgROM
+}
+
+// Battery returns a copy of PRG RAM.
+func (m *Mapper005) Battery() []byte {
+	return append([]byte(nil), m.prgRAM...)
+}
+
+// LoadBattery restores PRG RAM from a previous copy.
+func (m *Mapper005) LoadBattery(data []byte) {
+	copy(m.prgRAM, data)
+}
+
+// LoadTrainer copies a trainer to PRG RAM at $7000-$71ff.
+func (m *Mapper005) LoadTrainer(trainer Trainer) bool {
+	return m.prgRAM.loadTrainer(trainer)
+}
+
+// Nametables returns the nametables' mapping set by $5105. Nametables mapped
+// to ExRAM or fill mode are served by the mapper.
+func (m *Mapper005) Nametables() NametableMapping {
+	var mapping NametableMapping
+	for nt := range mapping {
+		switch src := m.ntSources >> uint(nt*2) & 3; src {
+		case mmc5CIRAMLower, mmc5CIRAMUpper:
+			mapping[nt] = int(src)
+		default:
+			mapping[nt] = CartridgeNametable
+		}
+	}
+
+	return mapping
+}
+
+// IRQ returns whether the scanline counter is asserting an interrupt.
+func (m *Mapper005) IRQ() bool {
+	return m.irqPending && m.irqEnabled
+}
+
+// RenderScanline counts the PPU's rendered scanlines, detecting the start of
+// the frame.
+//
+// The counter is reset on the frame's first scanline, and a pending interrupt
+// is flagged when it reaches the compare value set in $5203. The interrupt
+// stays pending, across frames, until it is acknowledged by reading $5204.
+func (m *Mapper005) RenderScanline(scanline int, tallSprites bool) {
+	m.scanline = scanline
+	m.tallSprites = tallSprites
+
+	if scanline < 0 {
+		m.inFrame = false
+		return
+	}
+
+	if !m.inFrame {
+		m.inFrame = true
+		m.counter = 0
+		return
+	}
+
+	m.counter++
+	if m.counter == m.irqCompare {
+		m.irqPending = true
+	}
+}
+
+// FetchNametable serves the vertical split and the extended attributes.
+func (m *Mapper005) FetchNametable(addr int, col int) (d byte, ok bool) {
+	index := addr & 0x3ff
+	attr := index >= 0x3c0
+
+	// Each tile's nametable fetch is followed by its pattern and attribute
+	// fetches
+	if !attr {
+		m.tileSplit = m.inSplit(col)
+		m.tileExAttr = m.exRAMMode == exRAMExtendedAttributes && !m.tileSplit
+		m.tileExAttrs = m.exRAM[index]
+	}
+
+	switch {
+	case m.tileSplit:
+		y := m.splitY()
+		if !attr {
+			return m.exRAM[y/8*32+col], true
+		}
+
+		at := m.exRAM[0x3c0+y/32*8+col/4]
+		return at >> uint(col%4/2*2+y%32/16*4) & 3 * 0x55, true
+
+	case attr && m.tileExAttr:
+		// The attribute's palette is used for all the tile's quadrants
+		return m.tileExAttrs >> 6 * 0x55, true
+	}
+
+	return 0, false
+}
+
+// ntSource returns the source of a nametable address ($2000-$2fff).
+func (m *Mapper005) ntSource(addr int) byte {
+	return m.ntSources >> uint((addr-0x2000)/NametableSize*2) & 3
+}
+
+// readNametable reads from a nametable address ($2000-$2fff) mapped to ExRAM
+// or fill mode.
+func (m *Mapper005) readNametable(addr int) byte {
+	index := addr % NametableSize
+
+	if m.ntSource(addr) == mmc5FillNametable {
+		if index >= 0x3c0 {
+			return m.fillAttr * 0x55
+		}
+		return m.fillTile
+	}
+
+	// ExRAM is only used as a nametable in the modes it is used for
+	// rendering
+	if m.exRAMMode > exRAMExtendedAttributes {
+		return 0
+	}
+	return m.exRAM[index]
+}
+
+// FetchPattern serves the PPU's pattern fetches from the sprites' or the
+// background's CHR banks, the split's bank or the extended attributes' bank.
+func (m *Mapper005) FetchPattern(addr int, sprite bool) byte {
+	if !sprite {
+		switch {
+		case m.tileSplit:
+			// The split scrolls separately from the background
+			addr = addr&^7 | m.splitY()%8
+			return m.readChr(m.splitBank*chrBankSize4k + addr%chrBankSize4k)
+
+		case m.tileExAttr:
+			bank := int(m.tileExAttrs&0x3f) | m.chrUpper<<6
+			return m.readChr(bank*chrBankSize4k + addr%chrBankSize4k)
+		}
+	}
+
+	return m.readChr(m.decodeChrAddr(addr, !sprite && m.tallSprites))
+}
+
+// inSplit returns whether a tile column is part of the vertical split.
+func (m *Mapper005) inSplit(col int) bool {
+	// The split is drawn from ExRAM, so it requires ExRAM to be used for
+	// rendering
+	if !m.splitEnabled || m.exRAMMode > exRAMExtendedAttributes {
+		return false
+	}
+
+	if m.splitRight {
+		return col >= m.splitTile
+	}
+	return col < m.splitTile
+}
+
+// splitY returns the vertical position within the split of the scanline being
+// rendered.
+func (m *Mapper005) splitY() int {
+	return (m.scanline + m.splitScroll) % 240
+}
+
+// prgBank returns the 8k bank mapped to a CPU address in $6000-$ffff, and
+// whether it is a PRG RAM bank.
+//
+// $6000-$7fff is always mapped to PRG RAM by $5113. $8000-$ffff is mapped by
+// $5114-$5117 according to the PRG mode, bit 7 of each register selecting PRG
+// ROM, except for $5117 which always maps PRG ROM.
+func (m *Mapper005) prgBank(addr int) (bank int, ram bool) {
+	if addr < 0x8000 {
+		return int(m.prgRegs[0] & 7), true
+	}
+
+	slot := (addr - 0x8000) / prgBankSize8k
+	regs := m.prgRegs[1:]
+
+	// Larger banks ignore the low bits of the bank number, mask
+	var reg, mask, offset int
+	switch m.prgMode {
+	case 0:
+		// A single 32k bank
+		reg, mask, offset = 3, 3, slot
+	case 1:
+		// 2 16k banks
+		reg, mask, offset = slot/2*2+1, 1, slot%2
+	case 2:
+		// A 16k bank followed by 2 8k banks
+		reg = slot
+		if slot < 2 {
+			reg, mask, offset = 1, 1, slot
+		}
+	case 3:
+		// 4 8k banks
+		reg = slot
+	}
+
+	d := int(regs[reg]&0x7f)&^mask + offset
+
+	if reg != 3 && regs[reg]>>7 == 0 {
+		return d & 7, true
+	}
+	return d % m.prgBanks, false
+}
+
+// decodeChrAddr returns the offset in CHR memory of a PPU address in
+// $0000-$1fff, from either the sprites' or the background's set of CHR banks.
+//
+// The bank size is set by the CHR mode, from 8k to 1k. The background's banks
+// only cover 4k, mirrored in both pattern tables, except in 8k mode.
+func (m *Mapper005) decodeChrAddr(addr int, bg bool) int {
+	size := 0x2000 >> m.chrMode
+
+	if bg && m.chrMode > 0 {
+		addr &= 0xfff
+	}
+
+	// Larger banks are set by the last register of their range
+	reg := (addr/size+1)*(8>>m.chrMode) - 1
+	if bg {
+		reg = 8 + reg%4
+	}
+
+	return (m.chrRegs[reg]*size + addr%size) % m.chrSize
+}
+
+func (m *Mapper005) readChr(index int) byte {
+	index %= m.chrSize
+	if m.useChrRAM {
+		return m.chrRAM[index]
+	}
+	return m.chrROM[index/ChrROMPageSize][index%ChrROMPageSize]
+}
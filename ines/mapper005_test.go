@@ -0,0 +1,79 @@
+package ines
+
+import (
+	"testing"
+)
+
+// renderFrame drives an MMC5 through a frame's visible scanlines, calling
+// check after each one, followed by vblank.
+func renderFrame(m *Mapper005, check func(scanline int)) {
+	for scanline := 0; scanline < 240; scanline++ {
+		m.RenderScanline(scanline, false)
+		check(scanline)
+	}
+	m.RenderScanline(-1, false)
+}
+
+func TestMapper005ScanlineIRQ(t *testing.T) {
+	for _, compare := range []int{1, 100, 239} {
+		m := newTestMapper(5, 8, 1).(*Mapper005)
+		m.Write(0x5203, byte(compare))
+		m.Write(0x5204, 0x80)
+
+		renderFrame(m, func(scanline int) {
+			status, _ := m.Observe(0x5204)
+			if status&0x40 == 0 {
+				t.Errorf("Compare %d: in frame bit clear on scanline %d",
+					compare, scanline)
+			}
+
+			want := scanline >= compare
+			if m.IRQ() != want {
+				t.Errorf("Compare %d: IRQ is %t on scanline %d, want %t",
+					compare, m.IRQ(), scanline, want)
+			}
+			if pending := status&0x80 != 0; pending != want {
+				t.Errorf("Compare %d: pending bit is %t on scanline %d, "+
+					"want %t", compare, pending, scanline, want)
+			}
+		})
+
+		status, _ := m.Observe(0x5204)
+		if status != 0x80 {
+			t.Errorf("Compare %d: status is $%02x in vblank, want $80",
+				compare, status)
+		}
+
+		// The unacknowledged interrupt stays pending into the next frame
+		m.RenderScanline(0, false)
+		if !m.IRQ() {
+			t.Errorf("Compare %d: IRQ dropped on the next frame", compare)
+		}
+
+		status, _ = m.Read(0x5204)
+		if status != 0xc0 {
+			t.Errorf("Compare %d: status is $%02x, want $c0", compare, status)
+		}
+		if m.IRQ() {
+			t.Errorf("Compare %d: IRQ not acknowledged by reading $5204",
+				compare)
+		}
+	}
+}
+
+func TestMapper005IRQDisabled(t *testing.T) {
+	m := newTestMapper(5, 8, 1).(*Mapper005)
+	m.Write(0x5203, 10)
+
+	renderFrame(m, func(scanline int) {
+		if m.IRQ() {
+			t.Fatalf("IRQ asserted on scanline %d while disabled", scanline)
+		}
+	})
+
+	// Enabling the interrupt asserts the pending one
+	m.Write(0x5204, 0x80)
+	if !m.IRQ() {
+		t.Error("Pending IRQ not asserted when enabled")
+	}
+}
@@ -0,0 +1,101 @@
+package ines
+
+// Mapper007 implements AxROM boards.
+//
+// AxROM switches 32k PRG ROM banks and selects one of the two nametables for
+// single screen mirroring. The board uses 8k of unbanked CHR RAM.
+//
+// Only some AxROM boards (AMROM) have bus conflicts, and games made for the
+// other boards rely on their absence, so bus conflicts aren't emulated.
+type Mapper007 struct {
+	prgROM []PrgROMPage
+
+	chrROM    []ChrROMPage
+	chrRAM    [ChrRAMSize]byte
+	useChrRAM bool
+
+	// prgBanks is the amount of 32k PRG ROM banks
+	prgBanks int
+
+	bank int
+
+	nametables
+}
+
+func (m *Mapper007) Read(addr int) (d byte, err error) {
+	switch {
+	case addr < 0x2000:
+		if m.useChrRAM {
+			return m.chrRAM[addr], nil
+		}
+		return m.chrROM[0][addr], nil
+
+	case addr < 0x3000:
+		return m.readNametable(addr), nil
+
+	case addr >= 0x8000:
+		addr -= 0x8000
+		page := m.bank*2 + addr/PrgROMPageSize
+		return m.prgROM[page][addr%PrgROMPageSize], nil
+
+	default:
+		return 0, nil
+	}
+}
+
+// Write handles writing to an address mapped by the mapper.
+//
+// Writing to $8000-$ffff selects the PRG ROM bank (bits 0-2) and the
+// nametable used (bit 4).
+func (m *Mapper007) Write(addr int, d byte) error {
+	if m.useChrRAM && addr < 0x2000 {
+		m.chrRAM[addr] = d
+	}
+
+	if addr >= 0x2000 && addr < 0x3000 {
+		m.writeNametable(addr, d)
+	}
+
+	if addr >= 0x8000 {
+		m.bank = int(d&7) % m.prgBanks
+
+		mirroring := SingleScreenLowerMirroring
+		if d>>4&1 == 1 {
+			mirroring = SingleScreenUpperMirroring
+		}
+		m.setMirroring(mirroring)
+	}
+
+	return nil
+}
+
+func (m *Mapper007) Observe(addr int) (d byte, err error) {
+	// There is no side effect to reading from mapper 007
+	return m.Read(addr)
+}
+
+func (m *Mapper007) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	// If only one page of prg rom, duplicate it to fill a 32k bank
+	if len(prgROM) == 1 {
+		var pageCopy PrgROMPage
+		copy(pageCopy[:], prgROM[0][:])
+
+		prgROM = append(prgROM, pageCopy)
+	}
+
+	if len(chrROM) == 0 {
+		m.useChrRAM = true
+	}
+
+	m.prgROM = prgROM
+	m.chrROM = chrROM
+
+	// A trailing 16k page of an odd amount of pages can't be switched in
+	m.prgBanks = len(prgROM) / 2
+
+	m.setMirroring(SingleScreenLowerMirroring)
+}
+
+func (m *Mapper007) GetPRGRom() []PrgROMPage {
+	return m.prgROM
+}
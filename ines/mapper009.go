@@ -0,0 +1,171 @@
+package ines
+
+const (
+	latchFD = 0xfd
+	latchFE = 0xfe
+)
+
+// latchedChr implements the CHR banking shared by the MMC2 and MMC4.
+//
+// Each 4k pattern table has two bank registers, one of which is selected by a
+// latch. The latch is switched when the PPU fetches tile $fd or $fe from the
+// pattern table, which the mappers observe.
+//
+// Without CHR ROM, 8k of unbanked CHR RAM is used instead.
+type latchedChr struct {
+	chrROM    []ChrROMPage
+	chrRAM    [ChrRAMSize]byte
+	useChrRAM bool
+
+	// banks holds the 4k bank registers of each pattern table, for latch
+	// values $fd and $fe
+	banks   [2][2]int
+	latches [2]byte
+}
+
+// populate loads the CHR ROM and resets the latches to $fe.
+func (c *latchedChr) populate(chrROM []ChrROMPage) {
+	c.chrROM = chrROM
+	c.useChrRAM = len(chrROM) == 0
+	c.latches = [2]byte{latchFE, latchFE}
+}
+
+func (c *latchedChr) read(addr int) byte {
+	if c.useChrRAM {
+		return c.chrRAM[addr]
+	}
+
+	pt := addr / chrBankSize4k
+
+	sel := 0
+	if c.latches[pt] == latchFE {
+		sel = 1
+	}
+
+	bank := c.banks[pt][sel] % (len(c.chrROM) * 2)
+	return c.chrROM[bank/2][bank%2*chrBankSize4k+addr%chrBankSize4k]
+}
+
+func (c *latchedChr) write(addr int, d byte) {
+	if c.useChrRAM {
+		c.chrRAM[addr] = d
+	}
+}
+
+// setBank sets one of the bank registers. reg selects the register in order of
+// $b000, $c000, $d000 and $e000.
+func (c *latchedChr) setBank(reg int, d byte) {
+	c.banks[reg/2][reg%2] = int(d & 0x1f)
+}
+
+// updateLatch sets a pattern table's latch if addr is within one of the
+// latch's trigger ranges.
+//
+// The trigger ranges are 8 bytes long, the second plane of tiles $fd and $fe,
+// unless exact is set for the pattern table, in which case only the first
+// byte of the range triggers the latch.
+func (c *latchedChr) updateLatch(addr int, exact [2]bool) {
+	pt := addr / chrBankSize4k
+	offset := addr % chrBankSize4k
+
+	if exact[pt] && offset&7 != 0 {
+		return
+	}
+
+	switch offset &^ 7 {
+	case 0xfd8:
+		c.latches[pt] = latchFD
+	case 0xfe8:
+		c.latches[pt] = latchFE
+	}
+}
+
+// Mapper009 implements the MMC2 (PxROM boards), used by Punch-Out!!.
+//
+// The MMC2 switches an 8k PRG ROM bank at $8000-$9fff, with the last 3 banks
+// fixed at $a000-$ffff, and switches CHR banks by latches triggered by the PPU's
+// pattern fetches.
+type Mapper009 struct {
+	prgROM []PrgROMPage
+	chr    latchedChr
+
+	prgBanks int
+	prgBank  int
+
+	nametables
+}
+
+func (m *Mapper009) Read(addr int) (d byte, err error) {
+	switch {
+	case addr < 0x2000:
+		return m.chr.read(addr), nil
+
+	case addr < 0x3000:
+		return m.readNametable(addr), nil
+
+	case addr >= 0x8000:
+		bank := m.prgBanks - 3 + (addr-0xa000)/prgBankSize8k
+		if addr < 0xa000 {
+			bank = m.prgBank
+		}
+
+		index := bank*prgBankSize8k + addr%prgBankSize8k
+		return m.prgROM[index/PrgROMPageSize][index%PrgROMPageSize], nil
+
+	default:
+		return 0, nil
+	}
+}
+
+// Write handles writing to an address mapped by the mapper.
+//
+// The MMC2's registers are mapped to $a000-$ffff, a register every 4k.
+func (m *Mapper009) Write(addr int, d byte) error {
+	switch {
+	case addr >= 0xf000:
+		mirroring := VerticalMirroring
+		if d&1 == 1 {
+			mirroring = HorizontalMirroring
+		}
+		m.setMirroring(mirroring)
+
+	case addr >= 0xb000:
+		m.chr.setBank((addr-0xb000)/0x1000, d)
+
+	case addr >= 0xa000:
+		m.prgBank = int(d&0xf) % m.prgBanks
+
+	case addr >= 0x2000 && addr < 0x3000:
+		m.writeNametable(addr, d)
+
+	case addr < 0x2000:
+		m.chr.write(addr, d)
+	}
+
+	return nil
+}
+
+func (m *Mapper009) Observe(addr int) (d byte, err error) {
+	// There is no side effect to reading from mapper 009, as the latches are
+	// only switched by the PPU's rendering fetches
+	return m.Read(addr)
+}
+
+func (m *Mapper009) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	m.prgROM = prgROM
+	m.chr.populate(chrROM)
+
+	m.prgBanks = len(prgROM) * PrgROMPageSize / prgBankSize8k
+}
+
+func (m *Mapper009) GetPRGRom() []PrgROMPage {
+	return m.prgROM
+}
+
+// PPUFetch switches the CHR latches when the PPU fetches tiles $fd or $fe.
+//
+// The MMC2 only triggers the first pattern table's latch on the exact
+// addresses $0fd8 and $0fe8.
+func (m *Mapper009) PPUFetch(addr int) {
+	m.chr.updateLatch(addr, [2]bool{true, false})
+}
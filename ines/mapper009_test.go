@@ -0,0 +1,105 @@
+package ines
+
+import (
+	"testing"
+)
+
+// newTestLatchedMapper creates an MMC2 or MMC4 with 8 4k CHR ROM banks, each
+// byte holding the number of its bank, and the banks for latches $fd and $fe
+// set to 1 and 2 in the first pattern table, and 3 and 4 in the second.
+func newTestLatchedMapper(num int) Mapper {
+	m, err := NewMapper(INESHeader{MapperNumber: num, PrgROMSize: 8,
+		ChrROMSize: 4, PrgRAMSize: SRAMSize})
+	if err != nil {
+		panic(err)
+	}
+
+	chrROM := make([]ChrROMPage, 4)
+	for i := range chrROM {
+		for j := range chrROM[i] {
+			chrROM[i][j] = byte((i*ChrROMPageSize + j) / chrBankSize4k)
+		}
+	}
+	m.Populate(newTestPrgROM(8), chrROM)
+
+	for i, addr := range []int{0xb000, 0xc000, 0xd000, 0xe000} {
+		m.Write(addr, byte(i+1))
+	}
+
+	return m
+}
+
+func TestLatchedChr(t *testing.T) {
+	tests := []struct {
+		name string
+		num  int
+		// triggers is the length of the first pattern table's latch trigger
+		// ranges
+		triggers int
+	}{
+		{"MMC2", 9, 1},
+		{"MMC4", 10, 8},
+	}
+
+	for _, test := range tests {
+		m := newTestLatchedMapper(test.num)
+		fetch := m.(PPUFetchObserver).PPUFetch
+
+		// Both latches start at $fe
+		for pt, want := range []byte{2, 4} {
+			if d, _ := m.Read(pt * chrBankSize4k); d != want {
+				t.Errorf("%s: pattern table %d reads bank %d on start, "+
+					"want %d", test.name, pt, d, want)
+			}
+		}
+
+		for pt := 0; pt < 2; pt++ {
+			base := pt * chrBankSize4k
+			triggers := 8
+			if pt == 0 {
+				triggers = test.triggers
+			}
+
+			for offset := 0xfd0; offset < 0xff0; offset++ {
+				// Fetching a trigger of one latch value switches from the
+				// other
+				for _, latch := range []int{0xfd8, 0xfe8} {
+					other := 0xfd8 + 0xfe8 - latch
+					fetch(base + other)
+					want, _ := m.Read(base)
+
+					fetch(base + offset)
+					if offset >= latch && offset < latch+triggers {
+						want = byte(pt*2 + 1)
+						if latch == 0xfe8 {
+							want++
+						}
+					}
+
+					if d, _ := m.Read(base); d != want {
+						t.Errorf("%s: pattern table %d reads bank %d after "+
+							"fetching $%04x, want %d", test.name, pt, d,
+							base+offset, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLatchedChrRAM(t *testing.T) {
+	for _, num := range []int{9, 10} {
+		m := newTestMapper(num, 8, 0)
+		fetch := m.(PPUFetchObserver).PPUFetch
+
+		for _, addr := range []int{0x0000, 0x0fd8, 0x1234, 0x1fe8} {
+			m.Write(addr, byte(addr))
+			fetch(addr)
+
+			if d, _ := m.Read(addr); d != byte(addr) {
+				t.Errorf("Mapper %d: CHR RAM reads $%02x at $%04x, "+
+					"want $%02x", num, d, addr, byte(addr))
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package ines
+
+// Mapper010 implements the MMC4 (FxROM boards), used by the Fire Emblem titles.
+//
+// The MMC4 is similar to the MMC2, but switches a 16k PRG ROM bank at
+// $8000-$bfff, with the last bank fixed at $c000-$ffff, and has 8k of PRG RAM.
+type Mapper010 struct {
+	prgROM []PrgROMPage
+	sRAM   prgRAM
+	chr    latchedChr
+
+	prgBank int
+
+	nametables
+}
+
+func (m *Mapper010) Read(addr int) (d byte, err error) {
+	switch {
+	case addr < 0x2000:
+		return m.chr.read(addr), nil
+
+	case addr < 0x3000:
+		return m.readNametable(addr), nil
+
+	case addr >= 0xc000:
+		return m.prgROM[len(m.prgROM)-1][addr-0xc000], nil
+
+	case addr >= 0x8000:
+		return m.prgROM[m.prgBank][addr-0x8000], nil
+
+	case addr >= 0x6000:
+		return m.sRAM.read(addr - 0x6000), nil
+
+	default:
+		return 0, nil
+	}
+}
+
+// Write handles writing to an address mapped by the mapper.
+//
+// The MMC4's registers are mapped to $a000-$ffff, a register every 4k.
+func (m *Mapper010) Write(addr int, d byte) error {
+	switch {
+	case addr >= 0xf000:
+		mirroring := VerticalMirroring
+		if d&1 == 1 {
+			mirroring = HorizontalMirroring
+		}
+		m.setMirroring(mirroring)
+
+	case addr >= 0xb000:
+		m.chr.setBank((addr-0xb000)/0x1000, d)
+
+	case addr >= 0xa000:
+		m.prgBank = int(d&0xf) % len(m.prgROM)
+
+	case addr >= 0x6000 && addr < 0x8000:
+		m.sRAM.write(addr-0x6000, d)
+
+	case addr >= 0x2000 && addr < 0x3000:
+		m.writeNametable(addr, d)
+
+	case addr < 0x2000:
+		m.chr.write(addr, d)
+	}
+
+	return nil
+}
+
+func (m *Mapper010) Observe(addr int) (d byte, err error) {
+	// There is no side effect to reading from mapper 010, as the latches are
+	// only switched by the PPU's rendering fetches
+	return m.Read(addr)
+}
+
+func (m *Mapper010) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	m.prgROM = prgROM
+	m.chr.populate(chrROM)
+}
+
+func (m *Mapper010) GetPRGRom() []PrgROMPage {
+	return m.prgROM
+}
+
+// Battery returns a copy of PRG RAM.
+func (m *Mapper010) Battery() []byte {
+	return append([]byte(nil), m.sRAM...)
+}
+
+// LoadBattery restores PRG RAM from a previous copy.
+func (m *Mapper010) LoadBattery(data []byte) {
+	copy(m.sRAM, data)
+}
+
+// LoadTrainer copies a trainer to PRG RAM at $7000-$71ff.
+func (m *Mapper010) LoadTrainer(trainer Trainer) bool {
+	return m.sRAM.loadTrainer(trainer)
+}
+
+// PPUFetch switches the CHR latches when the PPU fetches tiles $fd or $fe.
+func (m *Mapper010) PPUFetch(addr int) {
+	m.chr.updateLatch(addr, [2]bool{false, false})
+}
@@ -0,0 +1,98 @@
+package ines
+
+// Mapper011 implements Color Dreams boards.
+//
+// Color Dreams switches 32k PRG ROM banks and 8k CHR ROM banks with a single
+// register. ROMs without CHR ROM get 8k of unbanked CHR RAM instead.
+type Mapper011 struct {
+	prgROM []PrgROMPage
+
+	chrROM    []ChrROMPage
+	chrRAM    [ChrRAMSize]byte
+	useChrRAM bool
+
+	// prgBanks is the amount of 32k PRG ROM banks
+	prgBanks int
+
+	prgBank int
+	chrBank int
+
+	nametables
+}
+
+func (m *Mapper011) Read(addr int) (d byte, err error) {
+	switch {
+	case addr < 0x2000:
+		if m.useChrRAM {
+			return m.chrRAM[addr], nil
+		}
+		return m.chrROM[m.chrBank][addr], nil
+
+	case addr < 0x3000:
+		return m.readNametable(addr), nil
+
+	case addr >= 0x8000:
+		addr -= 0x8000
+		page := m.prgBank*2 + addr/PrgROMPageSize
+		return m.prgROM[page][addr%PrgROMPageSize], nil
+
+	default:
+		return 0, nil
+	}
+}
+
+// Write handles writing to an address mapped by the mapper.
+//
+// Writing to $8000-$ffff selects the PRG ROM bank (bits 0-1) and CHR ROM bank
+// (bits 4-7). The board has bus conflicts, so the value written is ANDed with
+// the ROM's value at addr.
+func (m *Mapper011) Write(addr int, d byte) error {
+	if m.useChrRAM && addr < 0x2000 {
+		m.chrRAM[addr] = d
+	}
+
+	if addr >= 0x2000 && addr < 0x3000 {
+		m.writeNametable(addr, d)
+	}
+
+	if addr >= 0x8000 {
+		rom, _ := m.Read(addr)
+		d &= rom
+
+		m.prgBank = int(d&3) % m.prgBanks
+		if !m.useChrRAM {
+			m.chrBank = int(d>>4) % len(m.chrROM)
+		}
+	}
+
+	return nil
+}
+
+func (m *Mapper011) Observe(addr int) (d byte, err error) {
+	// There is no side effect to reading from mapper 011
+	return m.Read(addr)
+}
+
+func (m *Mapper011) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	// If only one page of prg rom, duplicate it to fill a 32k bank
+	if len(prgROM) == 1 {
+		var pageCopy PrgROMPage
+		copy(pageCopy[:], prgROM[0][:])
+
+		prgROM = append(prgROM, pageCopy)
+	}
+
+	if len(chrROM) == 0 {
+		m.useChrRAM = true
+	}
+
+	m.prgROM = prgROM
+	m.chrROM = chrROM
+
+	// A trailing 16k page of an odd amount of pages can't be switched in
+	m.prgBanks = len(prgROM) / 2
+}
+
+func (m *Mapper011) GetPRGRom() []PrgROMPage {
+	return m.prgROM
+}
@@ -0,0 +1,207 @@
+package ines
+
+// bandaiMirroring maps the Bandai FCG's mirroring register to mirroring modes.
+var bandaiMirroring = [4]int{
+	VerticalMirroring,
+	HorizontalMirroring,
+	SingleScreenLowerMirroring,
+	SingleScreenUpperMirroring,
+}
+
+// bandaiFCG implements Bandai's FCG-1, FCG-2 and LZ93D50 ASICs, used by the
+// Dragon Ball and SD Gundam games.
+//
+// The FCG switches a 16k PRG ROM bank at $8000-$bfff, with the last bank
+// fixed at $c000-$ffff, and 8 1k CHR banks. It contains a 16 bit IRQ counter,
+// counting down every CPU cycle.
+//
+// The FCG-1 and FCG-2 map their registers to $6000-$7fff, and the LZ93D50 to
+// $8000-$ffff. Writing the IRQ counter through the LZ93D50's registers sets a
+// latch, which is copied to the counter when the IRQ is enabled. Boards with
+// the LZ93D50 save to a serial EEPROM instead of battery backed PRG RAM, which
+// is accessed through the same registers.
+type bandaiFCG struct {
+	prgROM []PrgROMPage
+
+	chrROM    []ChrROMPage
+	chrRAM    [ChrRAMSize]byte
+	useChrRAM bool
+
+	prgBanks int
+	chrBanks int
+
+	prg int
+	chr [8]int
+
+	nametables
+
+	irqLatch   uint16
+	irqCounter uint16
+	irqEnabled bool
+	irq        bool
+
+	eeprom eeprom
+}
+
+func (m *bandaiFCG) Read(addr int) (d byte, err error) {
+	switch {
+	case addr < 0x2000:
+		index := m.decodeChrAddr(addr)
+		if m.useChrRAM {
+			return m.chrRAM[index], nil
+		}
+		return m.chrROM[index/ChrROMPageSize][index%ChrROMPageSize], nil
+
+	case addr < 0x3000:
+		return m.readNametable(addr), nil
+
+	case addr >= 0x8000:
+		bank := m.prgBanks - 1
+		if addr < 0xc000 {
+			bank = m.prg % m.prgBanks
+		}
+		return m.prgROM[bank][addr%PrgROMPageSize], nil
+
+	case addr >= 0x6000:
+		// The EEPROM's data output is read on bit 4, the rest is open bus
+		if m.eeprom.out {
+			return 0x10, nil
+		}
+		return 0, nil
+
+	default:
+		return 0, nil
+	}
+}
+
+// Write handles writing to an address mapped by the mapper.
+//
+// The FCG's 14 registers are mirrored every 16 bytes in $6000-$7fff or
+// $8000-$ffff:
+//
+// 0 ~ 7 -> 1k CHR banks
+// 8 -> 16k PRG ROM bank
+// 9 -> mirroring
+// a -> IRQ control, bit 0 enabling the counter
+// b, c -> IRQ counter (or latch) low and high byte
+// d -> EEPROM control, bit 5 driving SCL and bit 6 driving SDA
+func (m *bandaiFCG) Write(addr int, d byte) error {
+	switch {
+	case addr < 0x2000:
+		if m.useChrRAM {
+			m.chrRAM[m.decodeChrAddr(addr)] = d
+		}
+		return nil
+
+	case addr < 0x3000:
+		m.writeNametable(addr, d)
+		return nil
+
+	case addr < 0x6000:
+		return nil
+	}
+
+	lz93d50 := addr >= 0x8000
+
+	switch reg := addr & 0xf; {
+	case reg < 8:
+		m.chr[reg] = int(d)
+
+	case reg == 8:
+		m.prg = int(d & 0xf)
+
+	case reg == 9:
+		m.setMirroring(bandaiMirroring[d&3])
+
+	case reg == 0xa:
+		m.irqEnabled = d&1 == 1
+		m.irq = false
+		if lz93d50 {
+			m.irqCounter = m.irqLatch
+		}
+
+	case reg == 0xb, reg == 0xc:
+		shift := uint(reg-0xb) * 8
+		mask := uint16(0xff) << shift
+		if lz93d50 {
+			m.irqLatch = m.irqLatch&^mask | uint16(d)<<shift
+		} else {
+			m.irqCounter = m.irqCounter&^mask | uint16(d)<<shift
+		}
+
+	case reg == 0xd:
+		m.eeprom.write(d>>5&1 == 1, d>>6&1 == 1)
+	}
+
+	return nil
+}
+
+func (m *bandaiFCG) Observe(addr int) (d byte, err error) {
+	// There is no side effect to reading from the FCG
+	return m.Read(addr)
+}
+
+func (m *bandaiFCG) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	if len(chrROM) == 0 {
+		m.useChrRAM = true
+	}
+
+	m.prgROM = prgROM
+	m.chrROM = chrROM
+
+	m.prgBanks = len(prgROM)
+	m.chrBanks = len(chrROM) * ChrROMPageSize / chrBankSize1k
+	if m.useChrRAM {
+		m.chrBanks = ChrRAMSize / chrBankSize1k
+	}
+}
+
+func (m *bandaiFCG) GetPRGRom() []PrgROMPage {
+	return m.prgROM
+}
+
+// Battery returns a copy of the EEPROM's contents.
+func (m *bandaiFCG) Battery() []byte {
+	return append([]byte(nil), m.eeprom.data...)
+}
+
+// LoadBattery restores the EEPROM's contents from a previous copy.
+func (m *bandaiFCG) LoadBattery(data []byte) {
+	copy(m.eeprom.data, data)
+}
+
+// IRQ returns whether the IRQ counter is asserting an interrupt.
+func (m *bandaiFCG) IRQ() bool {
+	return m.irq
+}
+
+// CPUCycle clocks the IRQ counter, which generates an interrupt when it
+// reaches 0.
+func (m *bandaiFCG) CPUCycle() {
+	if !m.irqEnabled {
+		return
+	}
+
+	m.irqCounter--
+	if m.irqCounter == 0 {
+		m.irq = true
+	}
+}
+
+// decodeChrAddr returns the offset in CHR memory of a PPU address in
+// $0000-$1fff.
+func (m *bandaiFCG) decodeChrAddr(addr int) int {
+	bank := m.chr[addr/chrBankSize1k] % m.chrBanks
+	return bank*chrBankSize1k + addr%chrBankSize1k
+}
+
+// Mapper016 implements Bandai's FCG boards, with a 24C02 EEPROM on the
+// LZ93D50 boards.
+type Mapper016 struct {
+	bandaiFCG
+}
+
+// Mapper159 implements Bandai's LZ93D50 boards with an X24C01 EEPROM.
+type Mapper159 struct {
+	bandaiFCG
+}
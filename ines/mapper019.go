@@ -0,0 +1,228 @@
+package ines
+
+// namco163CIRAMBanks is the lowest CHR bank number selecting a page of CIRAM
+// instead of CHR ROM.
+const namco163CIRAMBanks = 0xe0
+
+// Mapper019 implements the Namco 163 (and 129), used by most of Namco's later
+// Famicom games.
+//
+// The 163 switches 3 8k PRG ROM banks, with the last bank fixed at
+// $e000-$ffff, 8 1k CHR banks and the 4 nametables, which can each be mapped
+// to CIRAM or to a 1k bank of CHR ROM. It contains a 15 bit IRQ counter,
+// counting up every CPU cycle, and an expansion sound chip whose internal RAM
+// is accessed through $4800-$4fff.
+//
+// CHR banks can also select CIRAM for the pattern tables, which is unsupported
+// as the mapper has no access to the PPU's CIRAM. Those banks are read from CHR
+// ROM instead.
+type Mapper019 struct {
+	namco163Audio
+
+	prgROM []PrgROMPage
+	sRAM   prgRAM
+
+	chrROM    []ChrROMPage
+	chrRAM    [ChrRAMSize]byte
+	useChrRAM bool
+
+	prgBanks int
+	chrBanks int
+
+	prg [3]int
+	chr [8]int
+	nt  [4]int
+
+	// sRAMProtect holds the write protect register, which enables writing to
+	// PRG RAM when its high nibble is 4, except for the 2k windows whose bits
+	// are set in its low nibble
+	sRAMProtect byte
+
+	irqCounter uint16
+	irqEnabled bool
+	irq        bool
+}
+
+func (m *Mapper019) Read(addr int) (d byte, err error) {
+	if addr >= 0x4800 && addr < 0x5000 {
+		return m.readData(), nil
+	}
+	return m.read(addr), nil
+}
+
+func (m *Mapper019) read(addr int) byte {
+	switch {
+	case addr < 0x2000:
+		return m.readChr(m.chr[addr/chrBankSize1k], addr)
+
+	case addr < 0x3000:
+		nt := (addr - 0x2000) / NametableSize
+		return m.readChr(m.nt[nt], addr)
+
+	case addr >= 0x4800 && addr < 0x5000:
+		return m.ram[m.addr]
+
+	case addr >= 0x5000 && addr < 0x5800:
+		return byte(m.irqCounter)
+
+	case addr >= 0x5800 && addr < 0x6000:
+		d := byte(m.irqCounter >> 8)
+		if m.irqEnabled {
+			d |= 0x80
+		}
+		return d
+
+	case addr >= 0x6000 && addr < 0x8000:
+		return m.sRAM.read(addr - 0x6000)
+
+	case addr >= 0x8000:
+		bank := m.prgBanks - 1
+		if slot := (addr - 0x8000) / prgBankSize8k; slot < 3 {
+			bank = m.prg[slot]
+		}
+
+		index := (bank%m.prgBanks)*prgBankSize8k + addr%prgBankSize8k
+		return m.prgROM[index/PrgROMPageSize][index%PrgROMPageSize]
+
+	default:
+		return 0
+	}
+}
+
+// Write handles writing to an address mapped by the mapper.
+//
+// The 163's registers are mapped to $4800-$5fff and $8000-$ffff, each taking
+// a 2k range.
+func (m *Mapper019) Write(addr int, d byte) error {
+	switch {
+	case addr < 0x2000:
+		if m.useChrRAM {
+			bank := m.chr[addr/chrBankSize1k] % m.chrBanks
+			m.chrRAM[bank*chrBankSize1k+addr%chrBankSize1k] = d
+		}
+
+	case addr >= 0x4800 && addr < 0x5000:
+		m.writeData(d)
+
+	case addr >= 0x5000 && addr < 0x5800:
+		m.irqCounter = m.irqCounter&0x7f00 | uint16(d)
+		m.irq = false
+
+	case addr >= 0x5800 && addr < 0x6000:
+		m.irqCounter = m.irqCounter&0xff | uint16(d&0x7f)<<8
+		m.irqEnabled = d>>7 == 1
+		m.irq = false
+
+	case addr >= 0x6000 && addr < 0x8000:
+		window := uint(addr-0x6000) / 0x800
+		if m.sRAMProtect>>4 == 4 && m.sRAMProtect>>window&1 == 0 {
+			m.sRAM.write(addr-0x6000, d)
+		}
+
+	case addr >= 0x8000 && addr < 0xc000:
+		m.chr[(addr-0x8000)/0x800] = int(d)
+
+	case addr >= 0xc000 && addr < 0xe000:
+		m.nt[(addr-0xc000)/0x800] = int(d)
+
+	case addr >= 0xe000 && addr < 0xe800:
+		m.prg[0] = int(d & 0x3f)
+		m.namco163Audio.disabled = d>>6&1 == 1
+
+	case addr >= 0xe800 && addr < 0xf000:
+		m.prg[1] = int(d & 0x3f)
+
+	case addr >= 0xf000 && addr < 0xf800:
+		m.prg[2] = int(d & 0x3f)
+
+	case addr >= 0xf800:
+		m.sRAMProtect = d
+		m.setAddr(d)
+	}
+
+	return nil
+}
+
+func (m *Mapper019) Observe(addr int) (d byte, err error) {
+	// Observing doesn't increment the data port's address
+	return m.read(addr), nil
+}
+
+func (m *Mapper019) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	if len(chrROM) == 0 {
+		m.useChrRAM = true
+	}
+
+	m.prgROM = prgROM
+	m.chrROM = chrROM
+
+	m.prgBanks = len(prgROM) * PrgROMPageSize / prgBankSize8k
+	m.chrBanks = len(chrROM) * ChrROMPageSize / chrBankSize1k
+	if m.useChrRAM {
+		m.chrBanks = ChrRAMSize / chrBankSize1k
+	}
+}
+
+func (m *Mapper019) GetPRGRom() []PrgROMPage {
+	return m.prgROM
+}
+
+// Battery returns a copy of PRG RAM followed by the sound chip's internal RAM,
+// which some games also save to.
+func (m *Mapper019) Battery() []byte {
+	data := append([]byte(nil), m.sRAM...)
+	return append(data, m.ram[:]...)
+}
+
+// LoadBattery restores PRG RAM and the sound chip's internal RAM from a
+// previous copy.
+func (m *Mapper019) LoadBattery(data []byte) {
+	n := copy(m.sRAM, data)
+	copy(m.ram[:], data[n:])
+}
+
+// LoadTrainer copies a trainer to PRG RAM at $7000-$71ff.
+func (m *Mapper019) LoadTrainer(trainer Trainer) bool {
+	return m.sRAM.loadTrainer(trainer)
+}
+
+// Nametables returns the nametables' mapping, where nametables mapped to CHR
+// ROM are served by the mapper.
+func (m *Mapper019) Nametables() NametableMapping {
+	var mapping NametableMapping
+	for nt, bank := range m.nt {
+		mapping[nt] = CartridgeNametable
+		if bank >= namco163CIRAMBanks {
+			mapping[nt] = bank & 1
+		}
+	}
+
+	return mapping
+}
+
+// IRQ returns whether the IRQ counter is asserting an interrupt.
+func (m *Mapper019) IRQ() bool {
+	return m.irq
+}
+
+// CPUCycle clocks the IRQ counter, which generates an interrupt when it
+// reaches $7fff.
+func (m *Mapper019) CPUCycle() {
+	if !m.irqEnabled || m.irqCounter == 0x7fff {
+		return
+	}
+
+	m.irqCounter++
+	if m.irqCounter == 0x7fff {
+		m.irq = true
+	}
+}
+
+// readChr reads from a 1k CHR bank, at the offset of addr within the bank.
+func (m *Mapper019) readChr(bank int, addr int) byte {
+	index := bank%m.chrBanks*chrBankSize1k + addr%chrBankSize1k
+	if m.useChrRAM {
+		return m.chrRAM[index]
+	}
+	return m.chrROM[index/ChrROMPageSize][index%ChrROMPageSize]
+}
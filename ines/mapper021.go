@@ -0,0 +1,295 @@
+package ines
+
+// vrcLines decodes the register index (0 ~ 3) within a VRC register's 4k range
+// from the CPU address lines the board connects to the chip's A0 and A1 pins.
+type vrcLines func(addr int) int
+
+// The VRC2 and VRC4 boards connect different address lines to the chip's
+// register select pins. As a single mapper number covers several boards, the
+// lines of all of its boards are ORed together.
+var (
+	// VRC4a (A1, A2) and VRC4c (A6, A7)
+	vrc21Lines vrcLines = func(addr int) int {
+		return (addr>>1|addr>>6)&1 | (addr>>2|addr>>7)&1<<1
+	}
+	// VRC2a (A1, A0)
+	vrc22Lines vrcLines = func(addr int) int {
+		return addr>>1&1 | addr&1<<1
+	}
+	// VRC2b and VRC4f (A0, A1) and VRC4e (A2, A3)
+	vrc23Lines vrcLines = func(addr int) int {
+		return (addr|addr>>2)&1 | (addr>>1|addr>>3)&1<<1
+	}
+	// VRC2c and VRC4b (A1, A0) and VRC4d (A3, A2)
+	vrc25Lines vrcLines = func(addr int) int {
+		return (addr>>1|addr>>3)&1 | (addr|addr>>2)&1<<1
+	}
+)
+
+// vrcMirroring maps the mirroring bits of the Konami VRC chips' mirroring
+// control to mirroring modes.
+var vrcMirroring = [4]int{
+	VerticalMirroring,
+	HorizontalMirroring,
+	SingleScreenLowerMirroring,
+	SingleScreenUpperMirroring,
+}
+
+// vrc24 implements Konami's VRC2 and VRC4, which differ by the VRC4's IRQ
+// counter, PRG swap mode and larger CHR banks. The boards using either chip
+// under the same mapper number are emulated as VRC4, as it is a superset of the
+// VRC2.
+//
+// The VRC2 and VRC4 switch 2 8k PRG ROM banks and 8 1k CHR ROM banks.
+type vrc24 struct {
+	prgROM []PrgROMPage
+	sRAM   prgRAM
+
+	chrROM    []ChrROMPage
+	chrRAM    [ChrRAMSize]byte
+	useChrRAM bool
+
+	lines vrcLines
+	// vrc2a boards ignore the lowest CHR bank bit
+	vrc2a bool
+
+	prgBanks int
+	chrBanks int
+
+	prg     [2]int
+	prgSwap bool
+	chr     [8]int
+	sRAMOn  bool
+
+	nametables
+
+	irq vrcIRQ
+}
+
+func (m *vrc24) populate(prgROM []PrgROMPage, chrROM []ChrROMPage,
+	lines vrcLines, vrc2a bool) {
+
+	if len(chrROM) == 0 {
+		m.useChrRAM = true
+	}
+
+	m.prgROM = prgROM
+	m.chrROM = chrROM
+
+	m.lines = lines
+	m.vrc2a = vrc2a
+
+	m.prgBanks = len(prgROM) * PrgROMPageSize / prgBankSize8k
+	m.chrBanks = len(chrROM) * ChrROMPageSize / chrBankSize1k
+	if m.useChrRAM {
+		m.chrBanks = ChrRAMSize / chrBankSize1k
+	}
+
+	// Games without battery backed RAM don't always enable it
+	m.sRAMOn = true
+}
+
+func (m *vrc24) Read(addr int) (d byte, err error) {
+	switch {
+	case addr < 0x2000:
+		index := m.decodeChrAddr(addr)
+		if m.useChrRAM {
+			return m.chrRAM[index], nil
+		}
+		return m.chrROM[index/ChrROMPageSize][index%ChrROMPageSize], nil
+
+	case addr < 0x3000:
+		return m.readNametable(addr), nil
+
+	case addr >= 0x8000:
+		index := m.decodePrgROMAddr(addr)
+		return m.prgROM[index/PrgROMPageSize][index%PrgROMPageSize], nil
+
+	case addr >= 0x6000:
+		if !m.sRAMOn {
+			return 0, nil
+		}
+		return m.sRAM.read(addr - 0x6000), nil
+
+	default:
+		return 0, nil
+	}
+}
+
+// Write handles writing to an address mapped by the mapper.
+//
+// The chip's registers are mapped to $8000-$ffff, 4 registers every 4k.
+func (m *vrc24) Write(addr int, d byte) error {
+	switch {
+	case addr < 0x2000:
+		if m.useChrRAM {
+			m.chrRAM[m.decodeChrAddr(addr)] = d
+		}
+		return nil
+
+	case addr < 0x3000:
+		m.writeNametable(addr, d)
+		return nil
+
+	case addr >= 0x6000 && addr < 0x8000:
+		if m.sRAMOn {
+			m.sRAM.write(addr-0x6000, d)
+		}
+		return nil
+
+	case addr < 0x8000:
+		return nil
+	}
+
+	reg := m.lines(addr)
+
+	switch addr & 0xf000 {
+	case 0x8000:
+		m.prg[0] = int(d & 0x1f)
+
+	case 0x9000:
+		switch reg {
+		case 0:
+			m.setMirroring(vrcMirroring[d&3])
+		case 2:
+			m.sRAMOn = d&1 == 1
+			m.prgSwap = d>>1&1 == 1
+		}
+
+	case 0xa000:
+		m.prg[1] = int(d & 0x1f)
+
+	case 0xb000, 0xc000, 0xd000, 0xe000:
+		// Each bank is set by a pair of registers, the low nibble followed
+		// by the high bits
+		bank := int(addr>>12-0xb)*2 + reg/2
+		if reg%2 == 0 {
+			m.chr[bank] = m.chr[bank]&0x1f0 | int(d&0xf)
+		} else {
+			m.chr[bank] = m.chr[bank]&0xf | int(d&0x1f)<<4
+		}
+
+	case 0xf000:
+		switch reg {
+		case 0:
+			m.irq.latch = m.irq.latch&0xf0 | d&0xf
+		case 1:
+			m.irq.latch = m.irq.latch&0xf | d<<4
+		case 2:
+			m.irq.writeControl(d)
+		case 3:
+			m.irq.acknowledge()
+		}
+	}
+
+	return nil
+}
+
+func (m *vrc24) Observe(addr int) (d byte, err error) {
+	// There is no side effect to reading from the VRC2 and VRC4
+	return m.Read(addr)
+}
+
+func (m *vrc24) GetPRGRom() []PrgROMPage {
+	return m.prgROM
+}
+
+// Battery returns a copy of PRG RAM.
+func (m *vrc24) Battery() []byte {
+	return append([]byte(nil), m.sRAM...)
+}
+
+// LoadBattery restores PRG RAM from a previous copy.
+func (m *vrc24) LoadBattery(data []byte) {
+	copy(m.sRAM, data)
+}
+
+// LoadTrainer copies a trainer to PRG RAM at $7000-$71ff.
+func (m *vrc24) LoadTrainer(trainer Trainer) bool {
+	return m.sRAM.loadTrainer(trainer)
+}
+
+// IRQ returns whether the IRQ counter is asserting an interrupt.
+func (m *vrc24) IRQ() bool {
+	return m.irq.irq
+}
+
+// CPUCycle clocks the IRQ counter.
+func (m *vrc24) CPUCycle() {
+	m.irq.cycle()
+}
+
+// decodePrgROMAddr returns the offset in PRG ROM of a CPU address in
+// $8000-$ffff.
+//
+// $a000-$bfff is switched by the second PRG register and $e000-$ffff is fixed
+// to the last bank. The PRG swap mode swaps between the first PRG register and
+// the second to last bank in $8000-$9fff and $c000-$dfff.
+func (m *vrc24) decodePrgROMAddr(addr int) int {
+	var bank int
+
+	slot := (addr - 0x8000) / prgBankSize8k
+	if m.prgSwap && slot%2 == 0 {
+		slot ^= 2
+	}
+
+	switch slot {
+	case 0:
+		bank = m.prg[0]
+	case 1:
+		bank = m.prg[1]
+	case 2:
+		bank = m.prgBanks - 2
+	case 3:
+		bank = m.prgBanks - 1
+	}
+
+	return (bank%m.prgBanks)*prgBankSize8k + addr%prgBankSize8k
+}
+
+// decodeChrAddr returns the offset in CHR memory of a PPU address in
+// $0000-$1fff.
+func (m *vrc24) decodeChrAddr(addr int) int {
+	bank := m.chr[addr/chrBankSize1k]
+	if m.vrc2a {
+		bank >>= 1
+	}
+
+	return (bank%m.chrBanks)*chrBankSize1k + addr%chrBankSize1k
+}
+
+// Mapper021 implements Konami's VRC4a and VRC4c boards.
+type Mapper021 struct {
+	vrc24
+}
+
+func (m *Mapper021) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	m.populate(prgROM, chrROM, vrc21Lines, false)
+}
+
+// Mapper022 implements Konami's VRC2a boards.
+type Mapper022 struct {
+	vrc24
+}
+
+func (m *Mapper022) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	m.populate(prgROM, chrROM, vrc22Lines, true)
+}
+
+// Mapper023 implements Konami's VRC2b, VRC4e and VRC4f boards.
+type Mapper023 struct {
+	vrc24
+}
+
+func (m *Mapper023) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	m.populate(prgROM, chrROM, vrc23Lines, false)
+}
+
+// Mapper025 implements Konami's VRC2c, VRC4b and VRC4d boards.
+type Mapper025 struct {
+	vrc24
+}
+
+func (m *Mapper025) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	m.populate(prgROM, chrROM, vrc25Lines, false)
+}
@@ -0,0 +1,54 @@
+package ines
+
+import (
+	"testing"
+)
+
+func TestVRC24PrgBanks(t *testing.T) {
+	// The register holding the PRG swap mode on each mapper's boards
+	swapAddrs := []struct {
+		mapper int
+		addr   int
+	}{
+		{21, 0x9004},
+		{23, 0x9002},
+		{25, 0x9001},
+	}
+
+	tests := []struct {
+		swap byte
+		addr int
+		bank byte
+	}{
+		// The first PRG register at $8000 and the second to last bank at
+		// $c000
+		{0x00, 0x8000, 2},
+		{0x00, 0xa000, 5},
+		{0x00, 0xc000, 14},
+		{0x00, 0xe000, 15},
+
+		// The swap mode swaps $8000 and $c000, $a000 and $e000 are
+		// unaffected
+		{0x02, 0x8000, 14},
+		{0x02, 0xa000, 5},
+		{0x02, 0xc000, 2},
+		{0x02, 0xe000, 15},
+	}
+
+	for _, swap := range swapAddrs {
+		for _, test := range tests {
+			m := newTestMapper(swap.mapper, 8, 1)
+
+			m.Write(0x8000, 2)
+			m.Write(0xa000, 5)
+			m.Write(swap.addr, test.swap)
+
+			// The bank's last byte, as the vectors in $fffa-$ffff
+			addr := test.addr + prgBankSize8k - 1
+			if d, _ := m.Read(addr); d != test.bank {
+				t.Errorf("Mapper %d, swap mode $%02x: $%04x reads bank %d, "+
+					"want %d", swap.mapper, test.swap, addr, d, test.bank)
+			}
+		}
+	}
+}
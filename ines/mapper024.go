@@ -0,0 +1,191 @@
+package ines
+
+// vrc6 implements Konami's VRC6, used by Akumajou Densetsu (VRC6a) and the
+// Madara and Esper Dream 2 (VRC6b).
+//
+// The VRC6 switches a 16k and an 8k PRG ROM bank, with the last 8k bank fixed
+// at $e000-$ffff, and 8 1k CHR ROM banks. It contains the same IRQ counter as
+// the VRC4, and an expansion sound chip.
+type vrc6 struct {
+	vrc6Audio
+
+	prgROM []PrgROMPage
+	sRAM   prgRAM
+
+	chrROM []ChrROMPage
+
+	// swapLines is set for the VRC6b, which connects the CPU's A0 and A1 to the
+	// chip's A1 and A0
+	swapLines bool
+
+	prgBanks int
+	chrBanks int
+
+	prg16k int
+	prg8k  int
+	chr    [8]int
+	sRAMOn bool
+
+	nametables
+
+	irq vrcIRQ
+}
+
+func (m *vrc6) populate(prgROM []PrgROMPage, chrROM []ChrROMPage,
+	swapLines bool) {
+
+	m.prgROM = prgROM
+	m.chrROM = chrROM
+	m.swapLines = swapLines
+
+	m.prgBanks = len(prgROM) * PrgROMPageSize / prgBankSize8k
+	m.chrBanks = len(chrROM) * ChrROMPageSize / chrBankSize1k
+}
+
+func (m *vrc6) Read(addr int) (d byte, err error) {
+	switch {
+	case addr < 0x2000:
+		bank := m.chr[addr/chrBankSize1k] % m.chrBanks
+		index := bank*chrBankSize1k + addr%chrBankSize1k
+		return m.chrROM[index/ChrROMPageSize][index%ChrROMPageSize], nil
+
+	case addr < 0x3000:
+		return m.readNametable(addr), nil
+
+	case addr >= 0x8000:
+		var bank int
+		switch {
+		case addr < 0xc000:
+			bank = m.prg16k*2 + (addr-0x8000)/prgBankSize8k
+		case addr < 0xe000:
+			bank = m.prg8k
+		default:
+			bank = m.prgBanks - 1
+		}
+
+		index := (bank%m.prgBanks)*prgBankSize8k + addr%prgBankSize8k
+		return m.prgROM[index/PrgROMPageSize][index%PrgROMPageSize], nil
+
+	case addr >= 0x6000:
+		if !m.sRAMOn {
+			return 0, nil
+		}
+		return m.sRAM.read(addr - 0x6000), nil
+
+	default:
+		return 0, nil
+	}
+}
+
+// Write handles writing to an address mapped by the mapper.
+//
+// The chip's registers are mapped to $8000-$ffff, up to 4 registers every 4k.
+func (m *vrc6) Write(addr int, d byte) error {
+	switch {
+	case addr >= 0x2000 && addr < 0x3000:
+		m.writeNametable(addr, d)
+		return nil
+
+	case addr >= 0x6000 && addr < 0x8000:
+		if m.sRAMOn {
+			m.sRAM.write(addr-0x6000, d)
+		}
+		return nil
+
+	case addr < 0x8000:
+		return nil
+	}
+
+	reg := addr & 3
+	if m.swapLines {
+		reg = addr>>1&1 | addr&1<<1
+	}
+
+	switch addr & 0xf000 {
+	case 0x8000:
+		m.prg16k = int(d & 0xf)
+
+	case 0x9000, 0xa000:
+		m.vrc6Audio.write(addr, reg, d)
+
+	case 0xb000:
+		if reg != 3 {
+			m.vrc6Audio.write(addr, reg, d)
+			break
+		}
+
+		m.sRAMOn = d>>7 == 1
+		m.setMirroring(vrcMirroring[d>>2&3])
+
+	case 0xc000:
+		m.prg8k = int(d & 0x1f)
+
+	case 0xd000, 0xe000:
+		m.chr[(addr-0xd000)/0x1000*4+reg] = int(d)
+
+	case 0xf000:
+		switch reg {
+		case 0:
+			m.irq.latch = d
+		case 1:
+			m.irq.writeControl(d)
+		case 2:
+			m.irq.acknowledge()
+		}
+	}
+
+	return nil
+}
+
+func (m *vrc6) Observe(addr int) (d byte, err error) {
+	// There is no side effect to reading from the VRC6
+	return m.Read(addr)
+}
+
+func (m *vrc6) GetPRGRom() []PrgROMPage {
+	return m.prgROM
+}
+
+// Battery returns a copy of PRG RAM.
+func (m *vrc6) Battery() []byte {
+	return append([]byte(nil), m.sRAM...)
+}
+
+// LoadBattery restores PRG RAM from a previous copy.
+func (m *vrc6) LoadBattery(data []byte) {
+	copy(m.sRAM, data)
+}
+
+// LoadTrainer copies a trainer to PRG RAM at $7000-$71ff.
+func (m *vrc6) LoadTrainer(trainer Trainer) bool {
+	return m.sRAM.loadTrainer(trainer)
+}
+
+// IRQ returns whether the IRQ counter is asserting an interrupt.
+func (m *vrc6) IRQ() bool {
+	return m.irq.irq
+}
+
+// CPUCycle clocks the IRQ counter.
+func (m *vrc6) CPUCycle() {
+	m.irq.cycle()
+}
+
+// Mapper024 implements Konami's VRC6a board.
+type Mapper024 struct {
+	vrc6
+}
+
+func (m *Mapper024) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	m.populate(prgROM, chrROM, false)
+}
+
+// Mapper026 implements Konami's VRC6b board, whose register select lines are
+// swapped.
+type Mapper026 struct {
+	vrc6
+}
+
+func (m *Mapper026) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	m.populate(prgROM, chrROM, true)
+}
@@ -0,0 +1,221 @@
+package ines
+
+// fme7Mirroring maps the FME-7's mirroring register to mirroring modes.
+var fme7Mirroring = [4]int{
+	VerticalMirroring,
+	HorizontalMirroring,
+	SingleScreenLowerMirroring,
+	SingleScreenUpperMirroring,
+}
+
+// Mapper069 implements Sunsoft's FME-7 and 5B, used by Batman: Return of the
+// Joker, Gimmick! and Hebereke.
+//
+// The FME-7 switches 4 8k PRG banks, the first of which is mapped to
+// $6000-$7fff and can also select PRG RAM, with the last bank fixed at
+// $e000-$ffff, and 8 1k CHR banks. It contains a 16 bit IRQ counter, counting
+// down every CPU cycle.
+//
+// The 5B is an FME-7 with an expansion sound chip, whose registers are
+// harmless to the other boards.
+type Mapper069 struct {
+	sunsoft5BAudio
+
+	prgROM []PrgROMPage
+	sRAM   prgRAM
+
+	chrROM    []ChrROMPage
+	chrRAM    [ChrRAMSize]byte
+	useChrRAM bool
+
+	prgBanks int
+	chrBanks int
+
+	// command selects the register written through $a000-$bfff
+	command byte
+
+	prg [4]int
+	chr [8]int
+
+	// $6000-$7fff maps PRG RAM instead of ROM when sRAMSelected is set
+	sRAMSelected bool
+	sRAMEnabled  bool
+
+	nametables
+
+	irqCounter        uint16
+	irqEnabled        bool
+	irqCounterEnabled bool
+	irq               bool
+}
+
+func (m *Mapper069) Read(addr int) (d byte, err error) {
+	switch {
+	case addr < 0x2000:
+		index := m.decodeChrAddr(addr)
+		if m.useChrRAM {
+			return m.chrRAM[index], nil
+		}
+		return m.chrROM[index/ChrROMPageSize][index%ChrROMPageSize], nil
+
+	case addr < 0x3000:
+		return m.readNametable(addr), nil
+
+	case addr >= 0x6000 && addr < 0x8000 && m.sRAMSelected:
+		if !m.sRAMEnabled {
+			// Open bus
+			return 0, nil
+		}
+		return m.sRAM.read(addr - 0x6000), nil
+
+	case addr >= 0x6000:
+		bank := m.prgBanks - 1
+		if slot := (addr - 0x6000) / prgBankSize8k; slot < 4 {
+			bank = m.prg[slot]
+		}
+
+		index := (bank%m.prgBanks)*prgBankSize8k + addr%prgBankSize8k
+		return m.prgROM[index/PrgROMPageSize][index%PrgROMPageSize], nil
+
+	default:
+		return 0, nil
+	}
+}
+
+// Write handles writing to an address mapped by the mapper.
+//
+// The FME-7 is configured by writing a command number to $8000-$9fff, followed
+// by the command's parameter to $a000-$bfff. The 5B's sound registers are
+// selected and written the same way at $c000-$dfff and $e000-$ffff.
+func (m *Mapper069) Write(addr int, d byte) error {
+	switch {
+	case addr < 0x2000:
+		if m.useChrRAM {
+			m.chrRAM[m.decodeChrAddr(addr)] = d
+		}
+
+	case addr < 0x3000:
+		m.writeNametable(addr, d)
+
+	case addr >= 0x6000 && addr < 0x8000:
+		if m.sRAMSelected && m.sRAMEnabled {
+			m.sRAM.write(addr-0x6000, d)
+		}
+
+	case addr >= 0x8000 && addr < 0xa000:
+		m.command = d & 0xf
+
+	case addr >= 0xa000 && addr < 0xc000:
+		m.writeCommand(d)
+
+	case addr >= 0xc000 && addr < 0xe000:
+		m.sunsoft5BAudio.selectReg(d)
+
+	case addr >= 0xe000:
+		m.sunsoft5BAudio.write(d)
+	}
+
+	return nil
+}
+
+// writeCommand writes the parameter of the selected command:
+//
+// 0 ~ 7 -> 1k CHR banks
+// 8 -> $6000-$7fff bank, bit 6 selecting PRG RAM and bit 7 enabling it
+// 9 ~ b -> 8k PRG ROM banks at $8000, $a000 and $c000
+// c -> mirroring
+// d -> IRQ control, bit 0 enabling the interrupt and bit 7 the counter
+// e, f -> IRQ counter low and high byte
+func (m *Mapper069) writeCommand(d byte) {
+	switch c := m.command; {
+	case c < 8:
+		m.chr[c] = int(d)
+
+	case c == 8:
+		m.prg[0] = int(d & 0x3f)
+		m.sRAMSelected = d>>6&1 == 1
+		m.sRAMEnabled = d>>7 == 1
+
+	case c < 0xc:
+		m.prg[c-8] = int(d & 0x3f)
+
+	case c == 0xc:
+		m.setMirroring(fme7Mirroring[d&3])
+
+	case c == 0xd:
+		m.irqEnabled = d&1 == 1
+		m.irqCounterEnabled = d>>7 == 1
+		// Any write acknowledges a pending interrupt
+		m.irq = false
+
+	case c == 0xe:
+		m.irqCounter = m.irqCounter&0xff00 | uint16(d)
+
+	case c == 0xf:
+		m.irqCounter = m.irqCounter&0xff | uint16(d)<<8
+	}
+}
+
+func (m *Mapper069) Observe(addr int) (d byte, err error) {
+	// There is no side effect to reading from mapper 069
+	return m.Read(addr)
+}
+
+func (m *Mapper069) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	if len(chrROM) == 0 {
+		m.useChrRAM = true
+	}
+
+	m.prgROM = prgROM
+	m.chrROM = chrROM
+
+	m.prgBanks = len(prgROM) * PrgROMPageSize / prgBankSize8k
+	m.chrBanks = len(chrROM) * ChrROMPageSize / chrBankSize1k
+	if m.useChrRAM {
+		m.chrBanks = ChrRAMSize / chrBankSize1k
+	}
+}
+
+func (m *Mapper069) GetPRGRom() []PrgROMPage {
+	return m.prgROM
+}
+
+// Battery returns a copy of PRG RAM.
+func (m *Mapper069) Battery() []byte {
+	return append([]byte(nil), m.sRAM...)
+}
+
+// LoadBattery restores PRG RAM from a previous copy.
+func (m *Mapper069) LoadBattery(data []byte) {
+	copy(m.sRAM, data)
+}
+
+// LoadTrainer copies a trainer to PRG RAM at $7000-$71ff.
+func (m *Mapper069) LoadTrainer(trainer Trainer) bool {
+	return m.sRAM.loadTrainer(trainer)
+}
+
+// IRQ returns whether the IRQ counter is asserting an interrupt.
+func (m *Mapper069) IRQ() bool {
+	return m.irq
+}
+
+// CPUCycle clocks the IRQ counter, which generates an interrupt when it
+// underflows from $0000 to $ffff.
+func (m *Mapper069) CPUCycle() {
+	if !m.irqCounterEnabled {
+		return
+	}
+
+	m.irqCounter--
+	if m.irqCounter == 0xffff && m.irqEnabled {
+		m.irq = true
+	}
+}
+
+// decodeChrAddr returns the offset in CHR memory of a PPU address in
+// $0000-$1fff.
+func (m *Mapper069) decodeChrAddr(addr int) int {
+	bank := m.chr[addr/chrBankSize1k] % m.chrBanks
+	return bank*chrBankSize1k + addr%chrBankSize1k
+}
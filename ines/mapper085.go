@@ -0,0 +1,186 @@
+package ines
+
+// Mapper085 implements Konami's VRC7, used by Lagrange Point and Tiny Toon
+// Adventures 2.
+//
+// The VRC7 switches 3 8k PRG ROM banks, with the last bank fixed at
+// $e000-$ffff, and 8 1k CHR banks. It contains the same IRQ counter as the
+// VRC4.
+//
+// The VRC7's FM synthesis sound chip isn't emulated. Its registers are kept so
+// that writes to them are harmless, but the chip is silent.
+type Mapper085 struct {
+	prgROM []PrgROMPage
+	sRAM   prgRAM
+
+	chrROM    []ChrROMPage
+	chrRAM    [ChrRAMSize]byte
+	useChrRAM bool
+
+	prgBanks int
+	chrBanks int
+
+	prg    [3]int
+	chr    [8]int
+	sRAMOn bool
+
+	nametables
+
+	audioReg  byte
+	audioRegs [0x40]byte
+
+	irq vrcIRQ
+}
+
+func (m *Mapper085) Read(addr int) (d byte, err error) {
+	switch {
+	case addr < 0x2000:
+		index := m.decodeChrAddr(addr)
+		if m.useChrRAM {
+			return m.chrRAM[index], nil
+		}
+		return m.chrROM[index/ChrROMPageSize][index%ChrROMPageSize], nil
+
+	case addr < 0x3000:
+		return m.readNametable(addr), nil
+
+	case addr >= 0x8000:
+		bank := m.prgBanks - 1
+		if slot := (addr - 0x8000) / prgBankSize8k; slot < 3 {
+			bank = m.prg[slot]
+		}
+
+		index := (bank%m.prgBanks)*prgBankSize8k + addr%prgBankSize8k
+		return m.prgROM[index/PrgROMPageSize][index%PrgROMPageSize], nil
+
+	case addr >= 0x6000:
+		if !m.sRAMOn {
+			return 0, nil
+		}
+		return m.sRAM.read(addr - 0x6000), nil
+
+	default:
+		return 0, nil
+	}
+}
+
+// Write handles writing to an address mapped by the mapper.
+//
+// The chip's registers are mapped to $8000-$ffff in pairs, selected by the
+// address' range and A4 (VRC7a) or A3 (VRC7b).
+func (m *Mapper085) Write(addr int, d byte) error {
+	switch {
+	case addr < 0x2000:
+		if m.useChrRAM {
+			m.chrRAM[m.decodeChrAddr(addr)] = d
+		}
+		return nil
+
+	case addr < 0x3000:
+		m.writeNametable(addr, d)
+		return nil
+
+	case addr >= 0x6000 && addr < 0x8000:
+		if m.sRAMOn {
+			m.sRAM.write(addr-0x6000, d)
+		}
+		return nil
+
+	case addr < 0x8000:
+		return nil
+	}
+
+	reg := (addr>>4 | addr>>3) & 1
+
+	switch addr & 0xf000 {
+	case 0x8000:
+		m.prg[reg] = int(d & 0x3f)
+
+	case 0x9000:
+		switch {
+		case reg == 0:
+			m.prg[2] = int(d & 0x3f)
+		case addr&0x20 == 0:
+			m.audioReg = d & 0x3f
+		default:
+			m.audioRegs[m.audioReg] = d
+		}
+
+	case 0xa000, 0xb000, 0xc000, 0xd000:
+		m.chr[(addr-0xa000)/0x1000*2+reg] = int(d)
+
+	case 0xe000:
+		if reg == 1 {
+			m.irq.latch = d
+			break
+		}
+
+		m.sRAMOn = d>>7 == 1
+		m.setMirroring(vrcMirroring[d&3])
+
+	case 0xf000:
+		if reg == 0 {
+			m.irq.writeControl(d)
+		} else {
+			m.irq.acknowledge()
+		}
+	}
+
+	return nil
+}
+
+func (m *Mapper085) Observe(addr int) (d byte, err error) {
+	// There is no side effect to reading from mapper 085
+	return m.Read(addr)
+}
+
+func (m *Mapper085) Populate(prgROM []PrgROMPage, chrROM []ChrROMPage) {
+	if len(chrROM) == 0 {
+		m.useChrRAM = true
+	}
+
+	m.prgROM = prgROM
+	m.chrROM = chrROM
+
+	m.prgBanks = len(prgROM) * PrgROMPageSize / prgBankSize8k
+	m.chrBanks = len(chrROM) * ChrROMPageSize / chrBankSize1k
+	if m.useChrRAM {
+		m.chrBanks = ChrRAMSize / chrBankSize1k
+	}
+}
+
+func (m *Mapper085) GetPRGRom() []PrgROMPage {
+	return m.prgROM
+}
+
+// Battery returns a copy of PRG RAM.
+func (m *Mapper085) Battery() []byte {
+	return append([]byte(nil), m.sRAM...)
+}
+
+// LoadBattery restores PRG RAM from a previous copy.
+func (m *Mapper085) LoadBattery(data []byte) {
+	copy(m.sRAM, data)
+}
+
+// LoadTrainer copies a trainer to PRG RAM at $7000-$71ff.
+func (m *Mapper085) LoadTrainer(trainer Trainer) bool {
+	return m.sRAM.loadTrainer(trainer)
+}
+
+// IRQ returns whether the IRQ counter is asserting an interrupt.
+func (m *Mapper085) IRQ() bool {
+	return m.irq.irq
+}
+
+// CPUCycle clocks the IRQ counter.
+func (m *Mapper085) CPUCycle() {
+	m.irq.cycle()
+}
+
+// decodeChrAddr returns the offset in CHR memory of a PPU address in
+// $0000-$1fff.
+func (m *Mapper085) decodeChrAddr(addr int) int {
+	bank := m.chr[addr/chrBankSize1k] % m.chrBanks
+	return bank*chrBankSize1k + addr%chrBankSize1k
+}
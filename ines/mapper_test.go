@@ -0,0 +1,179 @@
+package ines
+
+import (
+	"testing"
+)
+
+// newTestPrgROM returns a PRG ROM of the given amount of 16k pages, with each
+// byte holding the number of the 8k bank it is in.
+func newTestPrgROM(pages int) []PrgROMPage {
+	prgROM := make([]PrgROMPage, pages)
+	for i := range prgROM {
+		for j := range prgROM[i] {
+			prgROM[i][j] = byte((i*PrgROMPageSize + j) / prgBankSize8k)
+		}
+	}
+
+	return prgROM
+}
+
+// newTestMapper creates the mapper registered for num, populated with a PRG
+// ROM from newTestPrgROM and empty CHR ROM pages.
+func newTestMapper(num int, prgPages int, chrPages int) Mapper {
+	header := INESHeader{
+		MapperNumber: num,
+		PrgROMSize:   prgPages,
+		ChrROMSize:   chrPages,
+		PrgRAMSize:   SRAMSize,
+	}
+
+	m, err := NewMapper(header)
+	if err != nil {
+		panic(err)
+	}
+	m.Populate(newTestPrgROM(prgPages), make([]ChrROMPage, chrPages))
+
+	return m
+}
+
+// TestMapperInstances checks that ROMs of the same mapper number don't share
+// their mapper's state.
+func TestMapperInstances(t *testing.T) {
+	tests := []struct {
+		num   int
+		write func(m Mapper)
+	}{
+		// Select PRG bank 5 at $8000
+		{4, func(m Mapper) { m.Write(0x8000, 6); m.Write(0x8001, 5) }},
+		// Select the second 32k bank, 8k banks 4-7
+		{7, func(m Mapper) { m.Write(0x8000, 1) }},
+		// Select PRG bank 5 at $8000
+		{69, func(m Mapper) { m.Write(0x8000, 9); m.Write(0xa000, 5) }},
+	}
+
+	for _, test := range tests {
+		a := newTestMapper(test.num, 8, 1)
+		b := newTestMapper(test.num, 8, 1)
+
+		test.write(a)
+		if d, _ := a.Read(0x8000); d == 0 {
+			t.Errorf("Mapper %d: bank register write didn't switch $8000",
+				test.num)
+		}
+		if d, _ := b.Read(0x8000); d != 0 {
+			t.Errorf("Mapper %d: another instance's write switched $8000 to "+
+				"bank %d", test.num, d)
+		}
+	}
+}
+
+func TestRegisterMapper(t *testing.T) {
+	const num = 0xfff
+	if MapperSupported(num) {
+		t.Fatalf("Mapper %d is already supported", num)
+	}
+	if _, err := NewMapper(INESHeader{MapperNumber: num}); err == nil {
+		t.Errorf("No error creating unregistered mapper %d", num)
+	}
+
+	var headers []INESHeader
+	RegisterMapper(num, func(header INESHeader) Mapper {
+		headers = append(headers, header)
+		return &Mapper000{}
+	})
+	defer func() {
+		mappersMu.Lock()
+		delete(mappers, num)
+		mappersMu.Unlock()
+	}()
+
+	a, err := NewMapper(INESHeader{MapperNumber: num, PrgROMSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewMapper(INESHeader{MapperNumber: num, PrgROMSize: 2})
+
+	if !MapperSupported(num) || a == b || len(headers) != 2 ||
+		headers[1].PrgROMSize != 2 {
+		t.Errorf("Registered factory wasn't called for each ROM's header")
+	}
+}
+
+// TestDiscreteMappers switches every bank of the discrete logic mappers on
+// ROMs with odd and even amounts of PRG ROM pages and no CHR ROM, which should
+// read and write CHR RAM.
+func TestDiscreteMappers(t *testing.T) {
+	// page returns the 16k page mapped to an 8k slot (0 ~ 3) after writing d,
+	// given the amount of pages after a lone page is duplicated
+	tests := []struct {
+		num  int
+		page func(d byte, slot, pages int) int
+	}{
+		{2, func(d byte, slot, pages int) int {
+			if slot >= 2 {
+				return pages - 1
+			}
+			return int(d) % pages
+		}},
+		{3, func(d byte, slot, pages int) int {
+			return slot / 2
+		}},
+		{7, func(d byte, slot, pages int) int {
+			return int(d&7)%(pages/2)*2 + slot/2
+		}},
+		{11, func(d byte, slot, pages int) int {
+			return int(d&3)%(pages/2)*2 + slot/2
+		}},
+		{66, func(d byte, slot, pages int) int {
+			return int(d>>4&3)%(pages/2)*2 + slot/2
+		}},
+	}
+
+	for _, test := range tests {
+		for _, prgPages := range []int{1, 3, 4, 8} {
+			m, err := NewMapper(INESHeader{
+				MapperNumber: test.num,
+				PrgROMSize:   prgPages,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Banks start with $ff, so writes to their first byte have no
+			// bus conflicts
+			prgROM := newTestPrgROM(prgPages)
+			for i := range prgROM {
+				prgROM[i][0] = 0xff
+				prgROM[i][prgBankSize8k] = 0xff
+			}
+			m.Populate(prgROM, nil)
+
+			pages := prgPages
+			if pages == 1 {
+				pages = 2
+			}
+
+			for d := 0; d < 0x100; d++ {
+				m.Write(0x8000, byte(d))
+
+				for slot := 0; slot < 4; slot++ {
+					addr := 0x8000 + (slot+1)*prgBankSize8k - 1
+					page := test.page(byte(d), slot, pages) % prgPages
+					want := byte(page*2 + slot%2)
+
+					if got, _ := m.Read(addr); got != want {
+						t.Errorf("Mapper %d, %d PRG pages: $%04x reads bank "+
+							"%d after writing $%02x, want %d", test.num,
+							prgPages, addr, got, d, want)
+					}
+				}
+
+				m.Write(0x1234, byte(d))
+				if got, _ := m.Read(0x1234); got != byte(d) {
+					t.Errorf("Mapper %d, %d PRG pages: CHR RAM reads $%02x, "+
+						"want $%02x", test.num, prgPages, got, d)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package ines
+
+import (
+	"github.com/m4ntis/bones/apu"
+)
+
+const (
+	// namco163RAMSize is the size of the Namco 163's internal RAM, holding
+	// both the waveforms and the channels' registers
+	namco163RAMSize = 0x80
+
+	// namco163ChannelPeriod is the amount of CPU cycles the 163 spends
+	// updating each of its enabled channels in turn
+	namco163ChannelPeriod = 15
+
+	// namco163MaxOutput is a channel's maximal output, a 4-bit sample
+	// multiplied by a 4-bit volume
+	namco163MaxOutput = 15 * 15
+)
+
+// namco163Audio implements the Namco 163's expansion sound, up to 8 wavetable
+// channels playing 4-bit samples from the chip's internal RAM.
+//
+// Each channel's registers take the 8 bytes from $40 + 8*n in the RAM, and the
+// channels are enabled from channel 7 downwards. The chip updates a single
+// channel every 15 CPU cycles, so the more channels enabled, the lower their
+// sample rate.
+type namco163Audio struct {
+	ram [namco163RAMSize]byte
+
+	// addr is the RAM address accessed through $4800-$4fff, incremented after
+	// each access if autoInc is set
+	addr    byte
+	autoInc bool
+
+	disabled bool
+
+	timer   int
+	channel int
+	outputs [8]int
+}
+
+// readData reads the RAM through the data port at $4800-$4fff.
+func (a *namco163Audio) readData() byte {
+	d := a.ram[a.addr]
+	a.incAddr()
+	return d
+}
+
+// writeData writes the RAM through the data port at $4800-$4fff.
+func (a *namco163Audio) writeData(d byte) {
+	a.ram[a.addr] = d
+	a.incAddr()
+}
+
+// setAddr sets the address port at $f800-$ffff, bit 7 enabling auto
+// increment.
+func (a *namco163Audio) setAddr(d byte) {
+	a.addr = d & 0x7f
+	a.autoInc = d>>7 == 1
+}
+
+func (a *namco163Audio) incAddr() {
+	if a.autoInc {
+		a.addr = (a.addr + 1) & 0x7f
+	}
+}
+
+// channels returns the amount of enabled channels.
+func (a *namco163Audio) channels() int {
+	return int(a.ram[0x7f]>>4&7) + 1
+}
+
+func (a *namco163Audio) ExpansionChip() apu.ExpansionChip {
+	return apu.Namco163
+}
+
+func (a *namco163Audio) ClockAudio() {
+	if a.disabled {
+		return
+	}
+
+	a.timer++
+	if a.timer < namco163ChannelPeriod {
+		return
+	}
+	a.timer = 0
+
+	first := 8 - a.channels()
+	if a.channel < first {
+		a.channel = 7
+	}
+	a.updateChannel(a.channel)
+	a.channel--
+}
+
+// updateChannel adds a channel's frequency to its phase, and outputs the
+// sample at its new phase.
+func (a *namco163Audio) updateChannel(n int) {
+	regs := a.ram[0x40+n*8 : 0x48+n*8]
+
+	freq := int(regs[0]) | int(regs[2])<<8 | int(regs[4]&3)<<16
+	phase := int(regs[1]) | int(regs[3])<<8 | int(regs[5])<<16
+	length := (256 - int(regs[4]&0xfc)) << 16
+
+	phase = (phase + freq) % length
+	regs[1] = byte(phase)
+	regs[3] = byte(phase >> 8)
+	regs[5] = byte(phase >> 16)
+
+	// Samples are packed 2 per byte, low nibble first
+	sampleAddr := (phase>>16 + int(regs[6])) & 0xff
+	sample := a.ram[sampleAddr/2] >> uint(sampleAddr%2*4) & 0xf
+
+	a.outputs[n] = int(sample) * int(regs[7]&0xf)
+}
+
+func (a *namco163Audio) AudioOutput() float32 {
+	if a.disabled {
+		return 0
+	}
+
+	// The chip cycles through the enabled channels fast enough for their
+	// outputs to be averaged
+	n := a.channels()
+	out := 0
+	for _, o := range a.outputs[8-n:] {
+		out += o
+	}
+
+	return float32(out) / float32(n*namco163MaxOutput)
+}
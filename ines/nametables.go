@@ -0,0 +1,102 @@
+package ines
+
+// NametableSize is the size of a single nametable, including its attribute
+// table.
+const NametableSize = 0x400 // 1k
+
+// Nametable pages a nametable can be mapped to
+const (
+	// CIRAMPageA and CIRAMPageB are the two 1k halves of the PPU's 2k of
+	// internal VRAM (CIRAM)
+	CIRAMPageA = 0
+	CIRAMPageB = 1
+
+	// CartridgeNametable marks a nametable that is served by the mapper's
+	// Read and Write, such as a four screen board's extra VRAM
+	CartridgeNametable = -1
+)
+
+// NametableMapping maps each of the PPU's 4 nametables, at $2000, $2400, $2800
+// and $2c00, to a page of CIRAM or to the cartridge.
+type NametableMapping [4]int
+
+var (
+	horizontalNametables = NametableMapping{
+		CIRAMPageA, CIRAMPageA, CIRAMPageB, CIRAMPageB}
+	verticalNametables = NametableMapping{
+		CIRAMPageA, CIRAMPageB, CIRAMPageA, CIRAMPageB}
+	singleScreenLowerNametables = NametableMapping{
+		CIRAMPageA, CIRAMPageA, CIRAMPageA, CIRAMPageA}
+	singleScreenUpperNametables = NametableMapping{
+		CIRAMPageB, CIRAMPageB, CIRAMPageB, CIRAMPageB}
+
+	// Four screen boards map the last 2 nametables to their own VRAM
+	fourScreenNametables = NametableMapping{
+		CIRAMPageA, CIRAMPageB, CartridgeNametable, CartridgeNametable}
+)
+
+// MirroredNametables returns the nametable mapping of a mirroring mode.
+func MirroredNametables(mirroring int) NametableMapping {
+	switch mirroring {
+	case HorizontalMirroring:
+		return horizontalNametables
+	case SingleScreenLowerMirroring:
+		return singleScreenLowerNametables
+	case SingleScreenUpperMirroring:
+		return singleScreenUpperNametables
+	default:
+		return verticalNametables
+	}
+}
+
+// nametables implements the nametable mapping of a mapper, starting from the
+// mirroring set by the ROM's header. Mappers that control the mirroring change
+// it with setMirroring, or set the mapping directly.
+//
+// Four screen boards have 2k of extra VRAM, which is mapped to the last 2
+// nametables regardless of the mapper's mirroring control.
+type nametables struct {
+	mapping NametableMapping
+
+	fourScreen bool
+	vram       [2 * NametableSize]byte
+}
+
+// newNametables returns the nametable mapping set by a ROM's header.
+func newNametables(header INESHeader) nametables {
+	n := nametables{
+		fourScreen: header.IgnoreMirror == 1,
+		mapping:    MirroredNametables(header.Mirroring),
+	}
+	if n.fourScreen {
+		n.mapping = fourScreenNametables
+	}
+
+	return n
+}
+
+// Nametables returns the current mapping of the nametables.
+func (n *nametables) Nametables() NametableMapping {
+	return n.mapping
+}
+
+// setMirroring maps the nametables according to a mirroring mode, unless the
+// board has four screen VRAM.
+func (n *nametables) setMirroring(mirroring int) {
+	if n.fourScreen {
+		return
+	}
+	n.mapping = MirroredNametables(mirroring)
+}
+
+// readNametable reads from a nametable address ($2000-$2fff) mapped to the
+// four screen VRAM.
+func (n *nametables) readNametable(addr int) byte {
+	return n.vram[(addr-0x2000)%len(n.vram)]
+}
+
+// writeNametable writes to a nametable address ($2000-$2fff) mapped to the
+// four screen VRAM.
+func (n *nametables) writeNametable(addr int, d byte) {
+	n.vram[(addr-0x2000)%len(n.vram)] = d
+}
@@ -0,0 +1,50 @@
+package ines
+
+// prgRAM is a cartridge's PRG RAM, sized by the ROM's header.
+//
+// RAM smaller than the range the mapper addresses is mirrored, and boards
+// without RAM read open bus.
+type prgRAM []byte
+
+// newPrgRAM allocates the PRG RAM of a ROM, both volatile and non volatile.
+//
+// As iNES headers don't reliably specify the RAM's size, at least ines1Size
+// bytes are allocated for them, being the most RAM the mapper's boards have.
+func newPrgRAM(header INESHeader, ines1Size int) prgRAM {
+	size := header.PrgRAMSize + header.PrgNVRAMSize
+	if !header.NES20 && size < ines1Size {
+		size = ines1Size
+	}
+
+	return make(prgRAM, size)
+}
+
+// loadTrainer copies a trainer to $7000-$71ff of the RAM as it is mapped at
+// power on, returning false if there is no RAM to copy it to.
+func (r prgRAM) loadTrainer(trainer Trainer) bool {
+	if len(r) == 0 {
+		return false
+	}
+
+	for i, d := range trainer {
+		r.write(trainerAddr-0x6000+i, d)
+	}
+	return true
+}
+
+// read reads the byte at index, relative to the RAM's start.
+func (r prgRAM) read(index int) byte {
+	if len(r) == 0 {
+		// Open bus
+		return 0
+	}
+	return r[index%len(r)]
+}
+
+// write writes the byte at index, relative to the RAM's start.
+func (r prgRAM) write(index int, d byte) {
+	if len(r) == 0 {
+		return
+	}
+	r[index%len(r)] = d
+}
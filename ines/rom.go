@@ -1,7 +1,14 @@
 package ines
 
+import (
+	"github.com/pkg/errors"
+)
+
 const (
 	TrainerSize = 512
+
+	// trainerAddr is the CPU address trainers are loaded to, in PRG RAM
+	trainerAddr = 0x7000
 )
 
 type Trainer [TrainerSize]byte
@@ -13,4 +20,62 @@ type ROM struct {
 
 	Trainer Trainer
 	Mapper  Mapper
+
+	// InDatabase is set if the ROM was found in the game database, in which
+	// case Header is corrected by its record, and the game's title and board
+	// name are taken from it when the record has them
+	InDatabase bool
+	Title      string
+	Board      string
+}
+
+// Dump is the contents of an iNES file, as read before creating the ROM's
+// mapper.
+//
+// PRG and CHR ROM are at their exact sizes, and Trainer is nil if the ROM has
+// none. The header and database fields are as in ROM.
+type Dump struct {
+	Header INESHeader
+
+	Trainer []byte
+	PrgROM  []byte
+	ChrROM  []byte
+
+	InDatabase bool
+	Title      string
+	Board      string
+}
+
+// ROM creates the dump's mapper and populates a ROM with the dump's data, or
+// returns an error if its mapper isn't supported.
+func (d *Dump) ROM() (*ROM, error) {
+	romMapper, err := NewMapper(d.Header)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while parsing iNes rom")
+	}
+
+	var trainer Trainer
+	copy(trainer[:], d.Trainer)
+
+	// NES 2.0 sizes that aren't whole pages leave the last page's end zeroed
+	prgROM := make([]PrgROMPage, d.Header.PrgROMSize)
+	for i := range prgROM {
+		copy(prgROM[i][:], d.PrgROM[i*PrgROMPageSize:])
+	}
+
+	chrROM := make([]ChrROMPage, d.Header.ChrROMSize)
+	for i := range chrROM {
+		copy(chrROM[i][:], d.ChrROM[i*ChrROMPageSize:])
+	}
+
+	romMapper.Populate(prgROM, chrROM)
+
+	return &ROM{
+		Header:     d.Header,
+		Trainer:    trainer,
+		Mapper:     romMapper,
+		InDatabase: d.InDatabase,
+		Title:      d.Title,
+		Board:      d.Board,
+	}, nil
 }
@@ -0,0 +1,228 @@
+package ines
+
+import (
+	"math"
+
+	"github.com/m4ntis/bones/apu"
+)
+
+// sunsoft5BDivider is the amount of CPU cycles per clock of the 5B's tone,
+// noise and envelope counters
+const sunsoft5BDivider = 16
+
+// sunsoft5BLevels maps the 5B's 32 output levels, spaced 1.5dB apart, to
+// linear amplitudes. Channel volumes v use level 2v+1.
+var sunsoft5BLevels = func() (levels [32]float32) {
+	for i := 1; i < len(levels); i++ {
+		levels[i] = float32(math.Pow(10, -float64(31-i)*1.5/20))
+	}
+	return levels
+}()
+
+// sunsoft5BTone implements one of the 5B's 3 square wave channels.
+type sunsoft5BTone struct {
+	period int
+	timer  int
+	out    bool
+
+	volume   byte
+	envelope bool
+
+	toneOff  bool
+	noiseOff bool
+}
+
+func (t *sunsoft5BTone) clock() {
+	t.timer++
+	if t.timer >= t.period {
+		t.timer = 0
+		t.out = !t.out
+	}
+}
+
+// sunsoft5BEnvelope implements the 5B's envelope generator, shared by the
+// channels in envelope mode. The envelope steps through 32 levels, its shape
+// selecting the direction and what happens once it's done.
+type sunsoft5BEnvelope struct {
+	period int
+	timer  int
+
+	step   byte
+	attack bool
+	done   bool
+
+	cont      bool
+	alternate bool
+	hold      bool
+}
+
+// setShape restarts the envelope with a new shape.
+func (e *sunsoft5BEnvelope) setShape(d byte) {
+	e.cont = d>>3&1 == 1
+	e.attack = d>>2&1 == 1
+	e.alternate = d>>1&1 == 1
+	e.hold = d&1 == 1
+
+	e.step = 0
+	e.timer = 0
+	e.done = false
+}
+
+func (e *sunsoft5BEnvelope) clock() {
+	if e.done {
+		return
+	}
+
+	e.timer++
+	if e.timer < e.period {
+		return
+	}
+	e.timer = 0
+
+	e.step++
+	if e.step < 32 {
+		return
+	}
+
+	switch {
+	case !e.cont:
+		e.done = true
+		e.attack = false
+	case e.hold:
+		e.done = true
+		e.attack = e.attack != e.alternate
+	case e.alternate:
+		e.attack = !e.attack
+	}
+	e.step = 0
+}
+
+func (e *sunsoft5BEnvelope) level() byte {
+	switch {
+	case e.done:
+		if e.attack {
+			return 31
+		}
+		return 0
+	case e.attack:
+		return e.step
+	default:
+		return 31 - e.step
+	}
+}
+
+// sunsoft5BAudio implements the Sunsoft 5B's expansion sound, a YM2149F
+// variant with 3 square wave channels, a noise generator and an envelope
+// generator.
+type sunsoft5BAudio struct {
+	reg byte
+
+	tones    [3]sunsoft5BTone
+	envelope sunsoft5BEnvelope
+
+	noisePeriod int
+	noiseTimer  int
+	noiseLFSR   uint32
+	noiseOut    bool
+
+	divider int
+}
+
+// selectReg selects the register written by write.
+func (a *sunsoft5BAudio) selectReg(d byte) {
+	a.reg = d
+}
+
+// write sets the selected register:
+//
+// 0 ~ 5 -> tone periods, low and high byte of each channel
+// 6 -> noise period
+// 7 -> tone and noise disable bits of each channel
+// 8 ~ a -> channel volumes and envelope enable bits
+// b, c -> envelope period low and high byte
+// d -> envelope shape
+func (a *sunsoft5BAudio) write(d byte) {
+	switch r := a.reg; {
+	case r < 6:
+		t := &a.tones[r/2]
+		if r%2 == 0 {
+			t.period = t.period&0xf00 | int(d)
+		} else {
+			t.period = t.period&0xff | int(d&0xf)<<8
+		}
+
+	case r == 6:
+		a.noisePeriod = int(d & 0x1f)
+
+	case r == 7:
+		for i := range a.tones {
+			a.tones[i].toneOff = d>>uint(i)&1 == 1
+			a.tones[i].noiseOff = d>>uint(i+3)&1 == 1
+		}
+
+	case r < 0xb:
+		t := &a.tones[r-8]
+		t.volume = d & 0xf
+		t.envelope = d>>4&1 == 1
+
+	case r == 0xb:
+		a.envelope.period = a.envelope.period&0xff00 | int(d)
+
+	case r == 0xc:
+		a.envelope.period = a.envelope.period&0xff | int(d)<<8
+
+	case r == 0xd:
+		a.envelope.setShape(d)
+	}
+}
+
+func (a *sunsoft5BAudio) ExpansionChip() apu.ExpansionChip {
+	return apu.Sunsoft5B
+}
+
+func (a *sunsoft5BAudio) ClockAudio() {
+	a.divider++
+	if a.divider < sunsoft5BDivider {
+		return
+	}
+	a.divider = 0
+
+	for i := range a.tones {
+		a.tones[i].clock()
+	}
+	a.envelope.clock()
+
+	// The noise generator is clocked at half the tones' rate
+	a.noiseTimer++
+	if a.noiseTimer >= 2*a.noisePeriod {
+		a.noiseTimer = 0
+		if a.noiseLFSR == 0 {
+			a.noiseLFSR = 1
+		}
+
+		// 17 bit LFSR, with taps at bits 0 and 3
+		bit := (a.noiseLFSR ^ a.noiseLFSR>>3) & 1
+		a.noiseLFSR = a.noiseLFSR>>1 | bit<<16
+		a.noiseOut = a.noiseLFSR&1 == 1
+	}
+}
+
+func (a *sunsoft5BAudio) AudioOutput() float32 {
+	var out float32
+	for _, t := range a.tones {
+		if !(t.out || t.toneOff) || !(a.noiseOut || t.noiseOff) {
+			continue
+		}
+
+		level := a.envelope.level()
+		if !t.envelope {
+			level = 0
+			if t.volume > 0 {
+				level = t.volume*2 + 1
+			}
+		}
+		out += sunsoft5BLevels[level]
+	}
+
+	return out / float32(len(a.tones))
+}
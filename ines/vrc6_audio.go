@@ -0,0 +1,185 @@
+package ines
+
+import (
+	"github.com/m4ntis/bones/apu"
+)
+
+// vrc6MaxOutput is the sum of the VRC6 channels' maximal outputs, 2 4-bit
+// pulses and a 5-bit sawtooth.
+const vrc6MaxOutput = 15 + 15 + 31
+
+// vrc6Pulse implements one of the VRC6's two pulse channels.
+//
+// The pulses have 16 steps and 8 duty cycles, and no envelope, sweep or length
+// counter. In digitized mode, the channel outputs its volume constantly.
+type vrc6Pulse struct {
+	volume    byte
+	duty      byte
+	digitized bool
+
+	period  int
+	timer   int
+	enabled bool
+
+	step byte
+}
+
+// write sets one of the channel's 3 registers, specified by reg (0 ~ 2).
+func (p *vrc6Pulse) write(reg int, d byte) {
+	switch reg {
+	case 0:
+		p.digitized = d>>7 == 1
+		p.duty = d >> 4 & 7
+		p.volume = d & 0xf
+	case 1:
+		p.period = p.period&0xf00 | int(d)
+	case 2:
+		p.period = p.period&0xff | int(d&0xf)<<8
+		p.enabled = d>>7 == 1
+		if !p.enabled {
+			// Disabling the channel resets its phase
+			p.step = 15
+		}
+	}
+}
+
+func (p *vrc6Pulse) clock(shift uint) {
+	if !p.enabled {
+		return
+	}
+
+	if p.timer > 0 {
+		p.timer--
+		return
+	}
+
+	p.timer = p.period >> shift
+	if p.step == 0 {
+		p.step = 15
+	} else {
+		p.step--
+	}
+}
+
+func (p *vrc6Pulse) output() byte {
+	if !p.enabled {
+		return 0
+	}
+	if p.digitized || p.step <= p.duty {
+		return p.volume
+	}
+	return 0
+}
+
+// vrc6Saw implements the VRC6's sawtooth channel.
+//
+// The sawtooth adds its rate to an accumulator every other clock, and resets
+// the accumulator after 7 additions. The channel outputs the accumulator's high
+// 5 bits.
+type vrc6Saw struct {
+	rate byte
+	acc  byte
+
+	period  int
+	timer   int
+	enabled bool
+
+	step byte
+}
+
+// write sets one of the channel's 3 registers, specified by reg (0 ~ 2).
+func (s *vrc6Saw) write(reg int, d byte) {
+	switch reg {
+	case 0:
+		s.rate = d & 0x3f
+	case 1:
+		s.period = s.period&0xf00 | int(d)
+	case 2:
+		s.period = s.period&0xff | int(d&0xf)<<8
+		s.enabled = d>>7 == 1
+		if !s.enabled {
+			s.acc = 0
+			s.step = 0
+		}
+	}
+}
+
+func (s *vrc6Saw) clock(shift uint) {
+	if !s.enabled {
+		return
+	}
+
+	if s.timer > 0 {
+		s.timer--
+		return
+	}
+
+	s.timer = s.period >> shift
+	s.step++
+	switch {
+	case s.step == 14:
+		s.acc = 0
+		s.step = 0
+	case s.step%2 == 0:
+		s.acc += s.rate
+	}
+}
+
+func (s *vrc6Saw) output() byte {
+	return s.acc >> 3
+}
+
+// vrc6Audio implements the VRC6's expansion sound, 2 pulse channels and a
+// sawtooth channel.
+type vrc6Audio struct {
+	pulses [2]vrc6Pulse
+	saw    vrc6Saw
+
+	// halt stops all channels' timers, and shift divides their periods by 16
+	// or 256
+	halt  bool
+	shift uint
+}
+
+// write sets the register at $9000-$b002, reg being the register's index
+// within its 4k range.
+func (a *vrc6Audio) write(addr int, reg int, d byte) {
+	switch addr & 0xf000 {
+	case 0x9000:
+		if reg == 3 {
+			a.halt = d&1 == 1
+			a.shift = 0
+			switch {
+			case d&4 != 0:
+				a.shift = 8
+			case d&2 != 0:
+				a.shift = 4
+			}
+			return
+		}
+		a.pulses[0].write(reg, d)
+	case 0xa000:
+		a.pulses[1].write(reg, d)
+	case 0xb000:
+		a.saw.write(reg, d)
+	}
+}
+
+func (a *vrc6Audio) ExpansionChip() apu.ExpansionChip {
+	return apu.VRC6
+}
+
+func (a *vrc6Audio) ClockAudio() {
+	if a.halt {
+		return
+	}
+
+	a.pulses[0].clock(a.shift)
+	a.pulses[1].clock(a.shift)
+	a.saw.clock(a.shift)
+}
+
+func (a *vrc6Audio) AudioOutput() float32 {
+	out := a.pulses[0].output() + a.pulses[1].output() + a.saw.output()
+	return float32(out) / vrc6MaxOutput
+}
@@ -0,0 +1,75 @@
+package ines
+
+const (
+	// vrcPrescalerPeriod is the amount of CPU cycles per scanline, multiplied
+	// by 3 to avoid fractions
+	vrcPrescalerPeriod = 341
+)
+
+// vrcIRQ implements the IRQ counter shared by Konami's VRC4, VRC6 and VRC7.
+//
+// The counter is clocked by the CPU, and counts either CPU cycles or
+// scanlines, which it approximates with a prescaler counting 113 2/3 CPU
+// cycles. The counter counts up from its latch, and generates an interrupt
+// when it overflows.
+type vrcIRQ struct {
+	latch   byte
+	counter byte
+
+	prescaler int
+
+	enabled     bool
+	enableOnAck bool
+	cycleMode   bool
+	irq         bool
+}
+
+// writeControl sets the counter's control register, reloading the counter if
+// it is enabled and acknowledging a pending interrupt.
+func (v *vrcIRQ) writeControl(d byte) {
+	v.enableOnAck = d&1 == 1
+	v.enabled = d>>1&1 == 1
+	v.cycleMode = d>>2&1 == 1
+
+	if v.enabled {
+		v.counter = v.latch
+		v.prescaler = vrcPrescalerPeriod
+	}
+
+	v.irq = false
+}
+
+// acknowledge acknowledges a pending interrupt, restoring the enabled flag
+// from the control register's enable on acknowledge bit.
+func (v *vrcIRQ) acknowledge() {
+	v.irq = false
+	v.enabled = v.enableOnAck
+}
+
+// cycle is called once every CPU cycle.
+func (v *vrcIRQ) cycle() {
+	if !v.enabled {
+		return
+	}
+
+	if v.cycleMode {
+		v.clock()
+		return
+	}
+
+	v.prescaler -= 3
+	if v.prescaler <= 0 {
+		v.prescaler += vrcPrescalerPeriod
+		v.clock()
+	}
+}
+
+func (v *vrcIRQ) clock() {
+	if v.counter == 0xff {
+		v.counter = v.latch
+		v.irq = true
+		return
+	}
+
+	v.counter++
+}
@@ -0,0 +1,33 @@
+package io
+
+import (
+	"fmt"
+	"time"
+)
+
+type BenchSpeaker struct {
+	sampleRate  int
+	sampleCount int
+
+	lastSPSUpdate time.Time
+}
+
+func NewBenchSpeaker(sampleRate int) *BenchSpeaker {
+	return &BenchSpeaker{sampleRate, 0, time.Now()}
+}
+
+// SampleRate returns the sample rate the bench speaker was created with.
+func (s *BenchSpeaker) SampleRate() int {
+	return s.sampleRate
+}
+
+// Play increments sample count.
+func (s *BenchSpeaker) Play(samples []float32) {
+	s.sampleCount += len(samples)
+
+	if time.Now().Sub(s.lastSPSUpdate) >= time.Second {
+		s.lastSPSUpdate = s.lastSPSUpdate.Add(time.Second)
+		fmt.Println("Samples per second:", s.sampleCount)
+		s.sampleCount = 0
+	}
+}
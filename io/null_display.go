@@ -0,0 +1,16 @@
+package io
+
+import (
+	"image"
+)
+
+// NullDisplay discards the frames it receives, allowing to run the NES
+// headlessly.
+type NullDisplay struct{}
+
+func NewNullDisplay() *NullDisplay {
+	return &NullDisplay{}
+}
+
+// Display does nothing.
+func (d *NullDisplay) Display(img image.Image) {}
@@ -0,0 +1,164 @@
+package io
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jfreymuth/pulse"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxRateDelta is the maximal relative change to the sample rate made to
+	// keep the buffer at its target fill level. Half a percent is well below
+	// the audible pitch change.
+	maxRateDelta = 0.005
+)
+
+// PulseSpeaker plays samples in real time through a PulseAudio sink.
+//
+// Samples are queued in a buffer that the sink drains at its own pace. Play
+// blocks while the buffer holds more than twice the requested latency, making
+// the speaker a clock source for the NES. As the NES is also throttled by the
+// display, the speaker adjusts the APU's resampling ratio slightly to keep the
+// buffer around its target fill level, preventing underflows and overflows
+// caused by the difference between the NES's and the display's refresh rates.
+type PulseSpeaker struct {
+	client *pulse.Client
+	stream *pulse.PlaybackStream
+
+	sampleRate int
+
+	buf []float32
+	// target is the buffer fill level, in samples, the speaker aims to keep
+	target int
+	last   float32
+	closed bool
+
+	m    sync.Mutex
+	cond *sync.Cond
+}
+
+// NewPulseSpeaker creates a PulseSpeaker playing to device at sampleRate.
+//
+// device is the name of a PulseAudio sink, or an empty string for the default
+// sink. latency is the target amount of audio buffered.
+func NewPulseSpeaker(device string, sampleRate int,
+	latency time.Duration) (*PulseSpeaker, error) {
+
+	if latency <= 0 {
+		return nil, errors.Errorf("Invalid audio latency %s", latency)
+	}
+
+	s := &PulseSpeaker{
+		sampleRate: sampleRate,
+		target:     int(latency.Seconds() * float64(sampleRate)),
+	}
+	s.cond = sync.NewCond(&s.m)
+
+	client, err := pulse.NewClient(pulse.ClientApplicationName("bones"))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to connect to PulseAudio")
+	}
+
+	opts := []pulse.PlaybackOption{
+		pulse.PlaybackMono,
+		pulse.PlaybackSampleRate(sampleRate),
+		pulse.PlaybackLatency(latency.Seconds()),
+	}
+
+	if device != "" {
+		sink, err := client.SinkByID(device)
+		if err != nil {
+			client.Close()
+			return nil, errors.Wrapf(err, "Failed to find audio device %s",
+				device)
+		}
+
+		opts = append(opts, pulse.PlaybackSink(sink))
+	}
+
+	stream, err := client.NewPlayback(pulse.Float32Reader(s.read), opts...)
+	if err != nil {
+		client.Close()
+		return nil, errors.Wrap(err, "Failed to create playback stream")
+	}
+
+	s.client = client
+	s.stream = stream
+	stream.Start()
+
+	return s, nil
+}
+
+// SampleRate returns the sample rate the speaker was created with.
+func (s *PulseSpeaker) SampleRate() int {
+	return s.sampleRate
+}
+
+// Play queues samples for playing, blocking while the buffer is full.
+func (s *PulseSpeaker) Play(samples []float32) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for len(s.buf) >= 2*s.target && !s.closed {
+		s.cond.Wait()
+	}
+	if s.closed {
+		return
+	}
+
+	s.buf = append(s.buf, samples...)
+}
+
+// RateRatio returns the factor by which the APU's sample rate should be scaled
+// to bring the buffer closer to its target fill level.
+func (s *PulseSpeaker) RateRatio() float64 {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	// Generate more samples when the buffer runs low, and less when it fills
+	// up
+	delta := float64(s.target-len(s.buf)) / float64(s.target)
+	if delta > 1 {
+		delta = 1
+	} else if delta < -1 {
+		delta = -1
+	}
+
+	return 1 + delta*maxRateDelta
+}
+
+// Close stops playing and disconnects from PulseAudio. Calls to Play return
+// immediately after Close.
+func (s *PulseSpeaker) Close() {
+	s.m.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.m.Unlock()
+
+	s.stream.Close()
+	s.client.Close()
+}
+
+// read fills out with buffered samples, called by the playback stream.
+//
+// If the buffer underflows, the rest of out is filled with the last sample
+// played to avoid popping.
+func (s *PulseSpeaker) read(out []float32) (int, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	n := copy(out, s.buf)
+	s.buf = append(s.buf[:0], s.buf[n:]...)
+
+	if n > 0 {
+		s.last = out[n-1]
+	}
+	for i := n; i < len(out); i++ {
+		out[i] = s.last
+	}
+
+	s.cond.Broadcast()
+	return len(out), nil
+}
@@ -0,0 +1,164 @@
+package io
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	wavHeaderSize    = 44
+	wavBitsPerSample = 16
+)
+
+// WAVSpeaker writes the samples it plays to a 16 bit mono PCM wav file.
+//
+// The speaker stops writing after a set amount of samples, signaling on the
+// channel returned by Done. The wav header's sizes are only valid after calling
+// Close.
+type WAVSpeaker struct {
+	w          io.WriteSeeker
+	sampleRate int
+
+	limit   int
+	written int
+	done    chan struct{}
+	closed  bool
+
+	err error
+	m   sync.Mutex
+}
+
+// NewWAVSpeaker creates a WAVSpeaker writing to w at a given sample rate,
+// writing at most limit samples.
+func NewWAVSpeaker(w io.WriteSeeker, sampleRate, limit int) (*WAVSpeaker, error) {
+	s := &WAVSpeaker{
+		w:          w,
+		sampleRate: sampleRate,
+
+		limit: limit,
+		done:  make(chan struct{}),
+	}
+
+	err := s.writeHeader()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to write wav header")
+	}
+
+	if limit == 0 {
+		close(s.done)
+	}
+
+	return s, nil
+}
+
+// SampleRate returns the sample rate the speaker was created with.
+func (s *WAVSpeaker) SampleRate() int {
+	return s.sampleRate
+}
+
+// Play writes samples to the wav file, until the sample limit is reached.
+func (s *WAVSpeaker) Play(samples []float32) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.closed || s.written == s.limit || s.err != nil {
+		return
+	}
+
+	if len(samples) > s.limit-s.written {
+		samples = samples[:s.limit-s.written]
+	}
+
+	pcm := make([]int16, len(samples))
+	for i, sample := range samples {
+		// Clip the sample to the 16 bit range
+		if sample > 1 {
+			sample = 1
+		} else if sample < -1 {
+			sample = -1
+		}
+		pcm[i] = int16(sample * 32767)
+	}
+
+	s.err = binary.Write(s.w, binary.LittleEndian, pcm)
+	s.written += len(samples)
+
+	if s.written == s.limit || s.err != nil {
+		close(s.done)
+	}
+}
+
+// Done returns a channel that's closed once the speaker is done writing
+// samples, either because the sample limit was reached or because of an error.
+func (s *WAVSpeaker) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close stops writing samples and updates the wav header with the written data
+// size, returning the first error the speaker encountered.
+func (s *WAVSpeaker) Close() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.closed {
+		return s.err
+	}
+	s.closed = true
+
+	if s.err != nil {
+		return errors.Wrap(s.err, "Failed to write samples")
+	}
+
+	dataSize := uint32(s.written * wavBitsPerSample / 8)
+
+	// Patch RIFF chunk size
+	_, err := s.w.Seek(4, io.SeekStart)
+	if err == nil {
+		err = binary.Write(s.w, binary.LittleEndian, 36+dataSize)
+	}
+
+	// Patch data chunk size
+	if err == nil {
+		_, err = s.w.Seek(wavHeaderSize-4, io.SeekStart)
+	}
+	if err == nil {
+		err = binary.Write(s.w, binary.LittleEndian, dataSize)
+	}
+
+	return errors.Wrap(err, "Failed to update wav header")
+}
+
+// writeHeader writes a wav header with empty sizes, to be updated on Close.
+func (s *WAVSpeaker) writeHeader() error {
+	blockAlign := wavBitsPerSample / 8
+
+	header := []interface{}{
+		[]byte("RIFF"),
+		uint32(36), // RIFF chunk size, updated on Close
+		[]byte("WAVE"),
+
+		[]byte("fmt "),
+		uint32(16),                        // fmt chunk size
+		uint16(1),                         // PCM format
+		uint16(1),                         // Mono
+		uint32(s.sampleRate),              // Sample rate
+		uint32(s.sampleRate * blockAlign), // Byte rate
+		uint16(blockAlign),                // Block align
+		uint16(wavBitsPerSample),          // Bits per sample
+
+		[]byte("data"),
+		uint32(0), // data chunk size, updated on Close
+	}
+
+	for _, field := range header {
+		err := binary.Write(s.w, binary.LittleEndian, field)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
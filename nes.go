@@ -3,11 +3,16 @@
 package bones
 
 import (
+	"log"
+	"sync"
+
+	"github.com/m4ntis/bones/apu"
 	"github.com/m4ntis/bones/asm"
 	"github.com/m4ntis/bones/cpu"
 	"github.com/m4ntis/bones/ines"
 	"github.com/m4ntis/bones/io"
 	"github.com/m4ntis/bones/ppu"
+	"github.com/m4ntis/bones/region"
 	"github.com/pkg/errors"
 )
 
@@ -26,16 +31,34 @@ const (
 	ModeDebug
 )
 
-// NES runs the CPU and PPU, providing a simple debugging API.
+// NES runs the CPU, PPU and APU, providing a simple debugging API.
 type NES struct {
 	// Breaks are published on this channel when run in ModeDebug.
 	Breaks chan Break
 
 	c *cpu.CPU
 	p *ppu.PPU
+	a *apu.APU
+
+	// irqMapper is set when the loaded ROM's mapper generates interrupts
+	irqMapper ines.IRQMapper
+	// clockedMapper is set when the loaded ROM's mapper counts CPU cycles
+	clockedMapper ines.ClockedMapper
+
+	region region.Region
 
-	running bool
-	stopc   chan struct{}
+	// The PPU runs ppuRatioNum/ppuRatioDen cycles per CPU cycle, ppuClock
+	// accumulating the fractions of PPU cycles that weren't yet run
+	ppuRatioNum int
+	ppuRatioDen int
+	ppuClock    int
+
+	// stopc is closed by Stop, and donec once the NES stopped running
+	stopc    chan struct{}
+	stopOnce sync.Once
+	donec    chan struct{}
+	// syncc receives the functions Sync runs between instructions
+	syncc chan func()
 
 	mode Mode
 
@@ -49,18 +72,37 @@ type NES struct {
 
 // New creates a runnable instance of an NES.
 //
+// disp and spk are where the NES outputs its frames and audio samples to. spk
+// may be nil, in which case no audio is generated.
+//
 // mode determines whether the NES will publish breaks and errors (ModeDebug)
 // or just just run the CPU and panic on error (ModeRun).
-func New(disp ppu.Displayer, ctrl *io.Controller, mode Mode) *NES {
+func New(disp ppu.Displayer, spk apu.Speaker, ctrl *io.Controller,
+	mode Mode) *NES {
+
 	p := ppu.New(disp)
-	c := cpu.New(p, ctrl)
+	a := apu.New(spk)
+	c := cpu.New(p, a, ctrl)
+
+	// The DMC channel fetches its samples from the CPU's memory
+	a.Mem = c.RAM
+
+	num, den := region.NTSC.PPUClockRatio()
 
 	return &NES{
 		c: c,
 		p: p,
+		a: a,
+
+		region:      region.NTSC,
+		ppuRatioNum: num,
+		ppuRatioDen: den,
 
-		running: false,
-		mode:    mode,
+		stopc: make(chan struct{}),
+		donec: make(chan struct{}),
+		syncc: make(chan func()),
+
+		mode: mode,
 
 		Breaks: make(chan Break),
 
@@ -69,17 +111,60 @@ func New(disp ppu.Displayer, ctrl *io.Controller, mode Mode) *NES {
 	}
 }
 
+// Load connects a ROM to the NES, and sets the NES's region to the one the
+// ROM's header specifies.
+//
+// ROMs with a trainer have it loaded into their mapper's PRG RAM at $7000. A
+// warning is logged if the mapper has no PRG RAM, and the trainer is ignored.
 func (n *NES) Load(rom *ines.ROM) {
+	if rom.Header.Trainer == 1 {
+		loadTrainer(rom)
+	}
+
 	n.p.Load(rom)
 	n.c.Load(rom)
+	n.SetRegion(rom.Header.Region())
+
+	// Mappers with an expansion sound chip are mixed with the APU
+	exp, _ := rom.Mapper.(apu.AudioExpansion)
+	n.a.SetExpansion(exp)
+
+	n.irqMapper, _ = rom.Mapper.(ines.IRQMapper)
+	n.clockedMapper, _ = rom.Mapper.(ines.ClockedMapper)
+}
+
+// loadTrainer loads a ROM's trainer into its mapper's PRG RAM, logging a
+// warning if it has none.
+func loadTrainer(rom *ines.ROM) {
+	m, ok := rom.Mapper.(ines.TrainerMapper)
+	if !ok || !m.LoadTrainer(rom.Trainer) {
+		log.Printf("Warning: mapper %d has no PRG RAM to load the ROM's "+
+			"trainer into, ignoring it", rom.Header.MapperNumber)
+	}
+}
+
+// SetRegion sets the region of the console the NES emulates, which determines
+// its CPU and PPU timing. It should be called before Start.
+func (n *NES) SetRegion(r region.Region) {
+	n.region = r
+	n.ppuRatioNum, n.ppuRatioDen = r.PPUClockRatio()
+	n.ppuClock = 0
+
+	n.p.SetRegion(r)
+	n.a.SetRegion(r)
+}
+
+// Region returns the region of the console the NES emulates.
+func (n *NES) Region() region.Region {
+	return n.region
 }
 
 // Start starts running the NES until Stop is called.
 //
-// Start is blocking and should be run in a goroutine of it's own.
+// Start is blocking and should be run in a goroutine of it's own. An NES can
+// only be started once.
 func (n *NES) Start() {
-	n.stopc = make(chan struct{})
-	n.running = true
+	defer close(n.donec)
 
 	go n.handleNmi()
 
@@ -91,11 +176,33 @@ func (n *NES) Start() {
 	n.startDebug()
 }
 
-// Stop sends a signal to stop the cpu on the next cycle.
+// Stop sends a signal to stop the cpu on the next cycle. It is safe to call
+// Stop more than once, from any goroutine.
 func (n *NES) Stop() {
-	if n.running {
+	n.stopOnce.Do(func() {
 		close(n.stopc)
-		n.running = false
+	})
+}
+
+// Sync runs f on the goroutine running the NES between two instructions, so
+// that f can access the state of the loaded ROM, such as its mapper's memory,
+// without racing with the running programme. Once the NES stopped running, f
+// is run on the calling goroutine.
+//
+// Sync blocks until f returns, waiting for the NES to start if it wasn't yet.
+// In ModeDebug, f isn't run while the NES is on a break.
+func (n *NES) Sync(f func()) {
+	done := make(chan struct{})
+	synced := func() {
+		f()
+		close(done)
+	}
+
+	select {
+	case n.syncc <- synced:
+		<-done
+	case <-n.donec:
+		f()
 	}
 }
 
@@ -157,12 +264,38 @@ func (n *NES) VRAM() *ppu.VRAM {
 	return n.p.VRAM
 }
 
+func (n *NES) APU() *apu.APU {
+	return n.a
+}
+
+// Mute silences the specified APU channels.
+func (n *NES) Mute(ch apu.Channel) {
+	n.a.Mute(ch)
+}
+
+// Unmute unmutes the specified APU channels.
+func (n *NES) Unmute(ch apu.Channel) {
+	n.a.Unmute(ch)
+}
+
+// Solo mutes all APU channels other than the specified ones.
+func (n *NES) Solo(ch apu.Channel) {
+	n.a.Solo(ch)
+}
+
+// ChannelStates returns a snapshot of the APU channels' registers.
+func (n *NES) ChannelStates() []apu.ChannelState {
+	return n.a.ChannelStates()
+}
+
 // startRun runs the CPU without checking breakpoints or errors.
 func (n *NES) startRun() {
 	for {
 		select {
 		case <-n.stopc:
 			return
+		case f := <-n.syncc:
+			f()
 		default:
 			n.execNext()
 		}
@@ -179,6 +312,8 @@ func (n *NES) startDebug() {
 		select {
 		case <-n.stopc:
 			return
+		case f := <-n.syncc:
+			f()
 		default:
 			n.handleBps()
 			n.handleError(n.execNextDebug())
@@ -190,9 +325,7 @@ func (n *NES) execNext() {
 	cycles, err := n.c.ExecNext()
 	panicOnErr(errors.Wrap(err, "Failed to execute next opcode"))
 
-	for i := 0; i < cycles*3; i++ {
-		n.p.Cycle()
-	}
+	n.clock(cycles)
 }
 
 func (n *NES) execNextDebug() error {
@@ -207,11 +340,32 @@ func (n *NES) execNextDebug() error {
 		return errors.Wrap(err, "Failed to execute next opcode")
 	}
 
-	for i := 0; i < cycles*3; i++ {
-		n.p.Cycle()
+	n.clock(cycles)
+	return nil
+}
+
+// clock runs the PPU and APU for the amount of CPU cycles the last opcode took,
+// 3 PPU cycles (3.2 on PAL) and a single APU cycle per CPU cycle. Mappers
+// counting CPU cycles are clocked along.
+func (n *NES) clock(cycles int) {
+	for i := 0; i < cycles; i++ {
+		n.ppuClock += n.ppuRatioNum
+		for ; n.ppuClock >= n.ppuRatioDen; n.ppuClock -= n.ppuRatioDen {
+			n.p.Cycle()
+		}
+
+		n.a.Cycle()
+
+		if n.clockedMapper != nil {
+			n.clockedMapper.CPUCycle()
+		}
 	}
 
-	return nil
+	n.c.SetIRQ(cpu.IRQFrameCounter, n.a.FrameIRQ())
+	n.c.SetIRQ(cpu.IRQDMC, n.a.DMCIRQ())
+	if n.irqMapper != nil {
+		n.c.SetIRQ(cpu.IRQMapper, n.irqMapper.IRQ())
+	}
 }
 
 func (n *NES) handleBps() {
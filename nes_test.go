@@ -1,42 +1,72 @@
-package bones_test
+package bones
 
 import (
-	"os"
+	"image"
+	"testing"
 
-	"github.com/m4ntis/bones"
 	"github.com/m4ntis/bones/ines"
 	"github.com/m4ntis/bones/io"
+	"github.com/m4ntis/bones/region"
 )
 
-const (
-	displayFPS = false
-	scale      = 4.0
-	filename   = "tetris.nes"
-)
+// frameCounter is a Displayer counting the frames it receives.
+type frameCounter struct {
+	frames int
+}
+
+func (d *frameCounter) Display(image.Image) {
+	d.frames++
+}
 
-func Example_runROM() {
-	// Open and parse ROM file
-	f, err := os.Open(filename)
-	panicOnErr(err)
+// newTestROM returns an NROM of empty PRG and CHR ROM.
+func newTestROM() *ines.ROM {
+	header := ines.INESHeader{PrgROMSize: 1, ChrROMSize: 1}
+	m, err := ines.NewMapper(header)
+	if err != nil {
+		panic(err)
+	}
+	m.Populate(make([]ines.PrgROMPage, 1), make([]ines.ChrROMPage, 1))
 
-	rom, err := ines.Parse(f)
-	panicOnErr(err)
+	return &ines.ROM{Header: header, Mapper: m}
+}
 
-	// Init I/O components
-	ctrl := new(io.Controller)
-	disp := io.NewDisplay(ctrl, displayFPS, scale)
+func TestClockPPURatio(t *testing.T) {
+	tests := []struct {
+		r region.Region
+		// frames holds the CPU cycles after which the first two frames are
+		// displayed, on the PPU cycle starting vblank. The PAL PPU runs 3.2
+		// cycles per CPU cycle, so a frame doesn't take a whole number of CPU
+		// cycles.
+		frames [2]int
+	}{
+		// Vblank starts on PPU cycle 241*341+2, and a frame takes 262*341
+		{region.NTSC, [2]int{27395, 57175}},
+		// Frames take 312*341 PPU cycles, 33247.5 CPU cycles
+		{region.PAL, [2]int{25683, 58930}},
+		// Vblank starts on PPU cycle 291*341+2
+		{region.Dendy, [2]int{33078, 68542}},
+	}
 
-	// Init NES
-	n := bones.New(disp, ctrl, bones.ModeRun)
-	n.Load(rom)
+	for _, test := range tests {
+		disp := &frameCounter{}
+		n := New(disp, nil, new(io.Controller), ModeRun)
+		n.Load(newTestROM())
+		n.SetRegion(test.r)
 
-	// Run ROM and display
-	go n.Start()
-	disp.Run()
-}
+		// Clocking a cycle at a time carries the fractions of PPU cycles over
+		// between calls
+		var frames []int
+		for cycles := 1; cycles <= test.frames[1]; cycles++ {
+			n.clock(1)
+			if disp.frames > len(frames) {
+				frames = append(frames, cycles)
+			}
+		}
 
-func panicOnErr(err error) {
-	if err != nil {
-		panic(err)
+		if len(frames) != 2 || frames[0] != test.frames[0] ||
+			frames[1] != test.frames[1] {
+			t.Errorf("%v: frames displayed after %v CPU cycles, want %v",
+				test.r, frames, test.frames)
+		}
 	}
 }
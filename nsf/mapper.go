@@ -0,0 +1,128 @@
+package nsf
+
+import (
+	"github.com/m4ntis/bones/ines"
+)
+
+const (
+	bankSize  = 0x1000 // 4k
+	bankCount = 8
+
+	sRAMAddr       = 0x6000
+	bankRegsAddr   = 0x5ff8
+	prgAddr        = 0x8000
+	trampolineAddr = 0x5ff0
+)
+
+// Mapper maps an NSF's data into the CPU's address space.
+//
+// The data is mapped to $8000-$ffff in 8 4k banks, which are switched by
+// writing to $5ff8-$5fff when the NSF uses bankswitching. $6000-$7fff is mapped
+// to 8k of RAM.
+//
+// Mapper also contains a small trampoline routine, used by the player to call
+// the NSF's init and play routines.
+type Mapper struct {
+	data  []byte
+	banks [bankCount]int
+
+	sRAM [ines.SRAMSize]byte
+
+	// trampoline holds a JSR opcode to the routine being called
+	trampoline [3]byte
+}
+
+// NewMapper creates a Mapper with the NSF's data loaded into it.
+func NewMapper(n *NSF) *Mapper {
+	m := &Mapper{}
+
+	if n.Header.Bankswitched() {
+		// The data is padded so that the load address' offset within a bank
+		// is kept
+		padding := n.Header.LoadAddr & (bankSize - 1)
+		m.data = make([]byte, padding+len(n.Data))
+		copy(m.data[padding:], n.Data)
+
+		for i, b := range n.Header.BankswitchInit {
+			m.banks[i] = int(b)
+		}
+	} else {
+		m.data = make([]byte, bankCount*bankSize)
+		copy(m.data[n.Header.LoadAddr-prgAddr:], n.Data)
+
+		for i := range m.banks {
+			m.banks[i] = i
+		}
+	}
+
+	// Pad the data to a whole bank
+	if len(m.data)%bankSize != 0 {
+		m.data = append(m.data, make([]byte, bankSize-len(m.data)%bankSize)...)
+	}
+
+	m.trampoline[0] = 0x20 // JSR
+
+	return m
+}
+
+func (m *Mapper) Read(addr int) (d byte, err error) {
+	switch {
+	case addr >= prgAddr:
+		bank := m.banks[(addr-prgAddr)/bankSize] % (len(m.data) / bankSize)
+		return m.data[bank*bankSize+addr%bankSize], nil
+
+	case addr >= sRAMAddr:
+		return m.sRAM[addr-sRAMAddr], nil
+
+	case addr >= trampolineAddr && addr < trampolineAddr+len(m.trampoline):
+		return m.trampoline[addr-trampolineAddr], nil
+	}
+
+	return 0, nil
+}
+
+func (m *Mapper) Write(addr int, d byte) error {
+	switch {
+	case addr >= prgAddr:
+		return nil
+
+	case addr >= sRAMAddr:
+		m.sRAM[addr-sRAMAddr] = d
+
+	case addr >= bankRegsAddr:
+		m.banks[addr-bankRegsAddr] = int(d)
+	}
+
+	return nil
+}
+
+func (m *Mapper) Observe(addr int) (d byte, err error) {
+	// There is no side effect to reading from the NSF mapper
+	return m.Read(addr)
+}
+
+// Nametables returns a vertical mirroring mapping, as there is no PPU when
+// playing NSFs.
+func (m *Mapper) Nametables() ines.NametableMapping {
+	return ines.MirroredNametables(ines.VerticalMirroring)
+}
+
+// Populate does nothing, as the NSF data is loaded on creation.
+func (m *Mapper) Populate([]ines.PrgROMPage, []ines.ChrROMPage) {}
+
+// GetPRGRom returns the NSF's data split into PRG ROM pages.
+func (m *Mapper) GetPRGRom() []ines.PrgROMPage {
+	prgROM := make([]ines.PrgROMPage,
+		(len(m.data)+ines.PrgROMPageSize-1)/ines.PrgROMPageSize)
+	for i := range prgROM {
+		copy(prgROM[i][:], m.data[i*ines.PrgROMPageSize:])
+	}
+
+	return prgROM
+}
+
+// setTrampoline sets the trampoline to call the routine at addr.
+func (m *Mapper) setTrampoline(addr int) {
+	m.trampoline[1] = byte(addr & 0xff)
+	m.trampoline[2] = byte(addr >> 8)
+}
@@ -0,0 +1,167 @@
+// Package nsf provides an api for NSF (NES Sound Format) parsing and playback
+package nsf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+
+	"github.com/m4ntis/bones/region"
+	"github.com/pkg/errors"
+)
+
+const (
+	NSFHeaderSize = 128
+
+	// Default play routine periods in microseconds, used when the header's
+	// speeds are 0
+	defaultPlaySpeed    = 16639
+	defaultPALPlaySpeed = 19997
+)
+
+// NSFHeader holds the metadata of an NSF file.
+type NSFHeader struct {
+	Version      int
+	TotalSongs   int
+	StartingSong int
+
+	LoadAddr int
+	InitAddr int
+	PlayAddr int
+
+	SongName  string
+	Artist    string
+	Copyright string
+
+	// Play routine periods, in microseconds
+	NTSCSpeed int
+	PALSpeed  int
+
+	BankswitchInit [8]byte
+
+	PAL       bool
+	DualPAL   bool
+	SoundChip byte
+}
+
+// NSF represents a whole NSF file, containing its header and music programme
+// data.
+type NSF struct {
+	Header NSFHeader
+	Data   []byte
+}
+
+// Bankswitched returns whether the NSF uses bankswitching, which is the case
+// when any of the header's initial bank values are non zero.
+func (h NSFHeader) Bankswitched() bool {
+	for _, b := range h.BankswitchInit {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Region returns the region the NSF is played in, being PAL only for NSFs that
+// don't support NTSC.
+func (h NSFHeader) Region() region.Region {
+	if h.PAL && !h.DualPAL {
+		return region.PAL
+	}
+	return region.NTSC
+}
+
+// PlaySpeed returns the play routine's period in microseconds in the NSF's
+// region.
+func (h NSFHeader) PlaySpeed() int {
+	if h.Region() == region.PAL {
+		return h.PALSpeed
+	}
+	return h.NTSCSpeed
+}
+
+// parseHeader parses the slice it gets into an NSFHeader struct.
+//
+// This method expects the slice to be of size 128 and panics if shorter.
+func parseHeader(headerBuff []byte) (header NSFHeader, err error) {
+	if !bytes.Equal(headerBuff[:5], []byte("NESM\x1a")) {
+		return NSFHeader{}, errors.Errorf("Incorrect NSF header prefix: %s",
+			hex.Dump(headerBuff[:5]))
+	}
+
+	le := binary.LittleEndian
+
+	header = NSFHeader{
+		Version:      int(headerBuff[5]),
+		TotalSongs:   int(headerBuff[6]),
+		StartingSong: int(headerBuff[7]),
+
+		LoadAddr: int(le.Uint16(headerBuff[8:])),
+		InitAddr: int(le.Uint16(headerBuff[0xa:])),
+		PlayAddr: int(le.Uint16(headerBuff[0xc:])),
+
+		SongName:  parseString(headerBuff[0xe:0x2e]),
+		Artist:    parseString(headerBuff[0x2e:0x4e]),
+		Copyright: parseString(headerBuff[0x4e:0x6e]),
+
+		NTSCSpeed: int(le.Uint16(headerBuff[0x6e:])),
+		PALSpeed:  int(le.Uint16(headerBuff[0x78:])),
+
+		PAL:       headerBuff[0x7a]&1 == 1,
+		DualPAL:   headerBuff[0x7a]&2 == 2,
+		SoundChip: headerBuff[0x7b],
+	}
+	copy(header.BankswitchInit[:], headerBuff[0x70:0x78])
+
+	if header.TotalSongs == 0 {
+		return NSFHeader{}, errors.New("NSF contains no songs")
+	}
+	if header.NTSCSpeed == 0 {
+		header.NTSCSpeed = defaultPlaySpeed
+	}
+	if header.PALSpeed == 0 {
+		header.PALSpeed = defaultPALPlaySpeed
+	}
+	if !header.Bankswitched() && header.LoadAddr < 0x8000 {
+		return NSFHeader{}, errors.Errorf("Invalid load address $%04x",
+			header.LoadAddr)
+	}
+
+	return header, nil
+}
+
+// parseString parses a null terminated string field.
+func parseString(field []byte) string {
+	n := bytes.IndexByte(field, 0)
+	if n < 0 {
+		n = len(field)
+	}
+	return string(field[:n])
+}
+
+// Parse reads an NSF file from r and populates an NSF struct with its data or
+// returns an error.
+func Parse(r io.Reader) (*NSF, error) {
+	headerBuff := make([]byte, NSFHeaderSize)
+	_, err := io.ReadFull(r, headerBuff)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while reading NSF header")
+	}
+
+	header, err := parseHeader(headerBuff)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while parsing NSF header")
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while reading NSF data")
+	}
+	if len(data) == 0 {
+		return nil, errors.New("NSF contains no data")
+	}
+
+	return &NSF{Header: header, Data: data}, nil
+}
@@ -0,0 +1,246 @@
+package nsf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/m4ntis/bones/region"
+)
+
+// testHeader returns an NSF header of 3 songs starting at the second, loaded,
+// initialized and played at $8000, $8010 and $8020, with the given speeds,
+// initial banks and region flags.
+func testHeader(ntscSpeed, palSpeed int, banks [8]byte,
+	regionFlags byte) []byte {
+
+	h := make([]byte, NSFHeaderSize)
+	le := binary.LittleEndian
+
+	copy(h, "NESM\x1a")
+	h[5] = 1
+	h[6] = 3
+	h[7] = 2
+	le.PutUint16(h[8:], 0x8000)
+	le.PutUint16(h[0xa:], 0x8010)
+	le.PutUint16(h[0xc:], 0x8020)
+	copy(h[0xe:], "Song")
+	// The artist field isn't null terminated
+	copy(h[0x2e:0x4e], bytes.Repeat([]byte{'a'}, 32))
+	copy(h[0x4e:], "2024")
+	le.PutUint16(h[0x6e:], uint16(ntscSpeed))
+	copy(h[0x70:], banks[:])
+	le.PutUint16(h[0x78:], uint16(palSpeed))
+	h[0x7a] = regionFlags
+	h[0x7b] = 0x01
+
+	return h
+}
+
+func TestParse(t *testing.T) {
+	var noBanks [8]byte
+	data := []byte{0x60}
+
+	file := append(testHeader(16666, 20000, noBanks, 0), data...)
+	n, err := Parse(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := NSFHeader{
+		Version:      1,
+		TotalSongs:   3,
+		StartingSong: 2,
+
+		LoadAddr: 0x8000,
+		InitAddr: 0x8010,
+		PlayAddr: 0x8020,
+
+		SongName:  "Song",
+		Artist:    string(bytes.Repeat([]byte{'a'}, 32)),
+		Copyright: "2024",
+
+		NTSCSpeed: 16666,
+		PALSpeed:  20000,
+
+		SoundChip: 0x01,
+	}
+	if n.Header != want {
+		t.Errorf("Header is\n%+v, want\n%+v", n.Header, want)
+	}
+	if !bytes.Equal(n.Data, data) {
+		t.Errorf("Data is %v, want %v", n.Data, data)
+	}
+}
+
+func TestParseHeaderDefaults(t *testing.T) {
+	tests := []struct {
+		name        string
+		regionFlags byte
+		region      region.Region
+		speed       int
+	}{
+		{"NTSC", 0, region.NTSC, defaultPlaySpeed},
+		{"PAL", 1, region.PAL, defaultPALPlaySpeed},
+		{"dual", 3, region.NTSC, defaultPlaySpeed},
+	}
+
+	for _, test := range tests {
+		h, err := parseHeader(testHeader(0, 0, [8]byte{}, test.regionFlags))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if h.NTSCSpeed != defaultPlaySpeed ||
+			h.PALSpeed != defaultPALPlaySpeed {
+			t.Errorf("%s: speeds are %d and %d, want the defaults", test.name,
+				h.NTSCSpeed, h.PALSpeed)
+		}
+		if h.Region() != test.region || h.PlaySpeed() != test.speed {
+			t.Errorf("%s: playing in %v at %d, want %v at %d", test.name,
+				h.Region(), h.PlaySpeed(), test.region, test.speed)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	noSongs := testHeader(0, 0, [8]byte{}, 0)
+	noSongs[6] = 0
+
+	lowLoad := testHeader(0, 0, [8]byte{}, 0)
+	binary.LittleEndian.PutUint16(lowLoad[8:], 0x6000)
+
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"prefix", append([]byte("NESM\x00"), make([]byte, 124)...)},
+		{"short header", testHeader(0, 0, [8]byte{}, 0)[:100]},
+		{"no songs", append(noSongs, 0x60)},
+		{"load address below $8000", append(lowLoad, 0x60)},
+		{"no data", testHeader(0, 0, [8]byte{}, 0)},
+	}
+
+	for _, test := range tests {
+		if _, err := Parse(bytes.NewReader(test.file)); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
+
+func TestMapperBankswitchInit(t *testing.T) {
+	banks := [8]byte{0, 1, 2, 3, 4, 5, 6, 7}
+	banks[0], banks[7] = 9, 2
+
+	h, err := parseHeader(testHeader(0, 0, banks, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.Bankswitched() {
+		t.Fatal("NSF with initial banks isn't bankswitched")
+	}
+
+	// Ten 4k banks, loaded $123 bytes into the first, each filled with its
+	// number from the load address on, except for a marker at its start
+	h.LoadAddr = 0x8123
+	data := make([]byte, 10*bankSize-0x123)
+	for i := range data {
+		data[i] = byte((i + 0x123) / bankSize)
+	}
+	data[0] = 0xaa
+	m := NewMapper(&NSF{Header: h, Data: data})
+
+	for slot, bank := range banks {
+		addr := prgAddr + slot*bankSize + bankSize - 1
+		if d, _ := m.Read(addr); d != bank {
+			t.Errorf("$%04x reads bank %d, want %d", addr, d, bank)
+		}
+	}
+
+	// Bank registers switch the slots, where the data starts at the load
+	// address' offset in its first bank
+	m.Write(bankRegsAddr, 0)
+	m.Write(bankRegsAddr+7, 4)
+	if d, _ := m.Read(0xffff); d != 4 {
+		t.Errorf("$ffff reads bank %d after switching, want 4", d)
+	}
+	if d, _ := m.Read(0x8122); d != 0 {
+		t.Errorf("$8122 before the load address reads $%02x, want 0", d)
+	}
+	if d, _ := m.Read(0x8123); d != 0xaa {
+		t.Errorf("$8123 reads $%02x, want the data's first byte $aa", d)
+	}
+}
+
+func TestMapperNotBankswitched(t *testing.T) {
+	h := testNSFHeader(t)
+	h.LoadAddr = 0xc000
+
+	m := NewMapper(&NSF{Header: h, Data: []byte{0xaa, 0xbb}})
+	if d, _ := m.Read(0xc001); d != 0xbb {
+		t.Errorf("$c001 reads $%02x, want $bb", d)
+	}
+	if d, _ := m.Read(0xbfff); d != 0 {
+		t.Errorf("$bfff below the load address reads $%02x, want 0", d)
+	}
+}
+
+func TestPlayerRegion(t *testing.T) {
+	// The init routine stores X to $6000, and the play routine returns
+	prg := make([]byte, 0x21)
+	copy(prg[0x10:], []byte{0x8e, 0x00, 0x60, 0x60}) // STX $6000; RTS
+	prg[0x20] = 0x60                                 // RTS
+
+	tests := []struct {
+		name        string
+		regionFlags byte
+		x           byte
+		period      float64
+	}{
+		{"NTSC", 0, 0, 16000 * region.NTSC.CPUClockRate() / 1e6},
+		{"PAL", 1, 1, 20000 * region.PAL.CPUClockRate() / 1e6},
+		{"dual", 3, 0, 16000 * region.NTSC.CPUClockRate() / 1e6},
+	}
+
+	for _, test := range tests {
+		h, err := parseHeader(testHeader(16000, 20000, [8]byte{},
+			test.regionFlags))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p := NewPlayer(&NSF{Header: h, Data: prg}, nil)
+		if p.playPeriod != test.period {
+			t.Errorf("%s: play period is %v cycles, want %v", test.name,
+				p.playPeriod, test.period)
+		}
+
+		if err := p.Init(2); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if x, _ := p.m.Read(sRAMAddr); x != test.x {
+			t.Errorf("%s: init routine got X %d, want %d", test.name, x,
+				test.x)
+		}
+		if err := p.Step(); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+
+	p := NewPlayer(&NSF{Header: testNSFHeader(t), Data: prg}, nil)
+	if err := p.Init(4); err == nil {
+		t.Error("No error initializing track 4 of 3")
+	}
+}
+
+// testNSFHeader returns the parsed header of testHeader with default speeds,
+// no initial banks and NTSC timing.
+func testNSFHeader(t *testing.T) NSFHeader {
+	h, err := parseHeader(testHeader(0, 0, [8]byte{}, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
@@ -0,0 +1,146 @@
+package nsf
+
+import (
+	"github.com/m4ntis/bones/apu"
+	"github.com/m4ntis/bones/cpu"
+	"github.com/m4ntis/bones/io"
+	"github.com/m4ntis/bones/ppu"
+	"github.com/m4ntis/bones/region"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxRoutineCycles is the amount of cycles after which a routine that
+	// hasn't returned is considered stuck, about a second's worth
+	maxRoutineCycles = 1789773
+)
+
+// Player plays an NSF's tracks by running its init and play routines on the
+// CPU, clocking the APU alongside it.
+//
+// The NSF is played in the region returned by its header's Region, at that
+// region's clock rate and play speed.
+//
+// The player doesn't use a PPU, as NSF programmes only drive the APU. A PPU is
+// still connected to the CPU, but isn't clocked.
+type Player struct {
+	nsf *NSF
+
+	m *Mapper
+	c *cpu.CPU
+	a *apu.APU
+
+	// playPeriod is the amount of CPU cycles between play routine calls
+	playPeriod float64
+	// cycleDebt is the amount of cycles left to run until the next play call
+	cycleDebt float64
+}
+
+// NewPlayer creates a Player for an NSF, outputting its audio to spk.
+func NewPlayer(n *NSF, spk apu.Speaker) *Player {
+	a := apu.New(spk)
+	c := cpu.New(ppu.New(nil), a, new(io.Controller))
+	m := NewMapper(n)
+
+	c.RAM.Mapper = m
+	a.Mem = c.RAM
+
+	r := n.Header.Region()
+	a.SetRegion(r)
+
+	return &Player{
+		nsf: n,
+
+		m: m,
+		c: c,
+		a: a,
+
+		playPeriod: float64(n.Header.PlaySpeed()) * r.CPUClockRate() / 1e6,
+	}
+}
+
+// APU returns the player's APU.
+func (p *Player) APU() *apu.APU {
+	return p.a
+}
+
+// Init initializes a track, numbered 1 to the NSF's total song count, by
+// running the NSF's init routine.
+//
+// Init should be called once, before calling Step.
+func (p *Player) Init(track int) error {
+	if track < 1 || track > p.nsf.Header.TotalSongs {
+		return errors.Errorf("Invalid track %d, NSF contains %d tracks", track,
+			p.nsf.Header.TotalSongs)
+	}
+
+	// Initialize the APU's registers
+	for addr := 0x4000; addr <= 0x4013; addr++ {
+		p.c.RAM.MustWrite(addr, 0)
+	}
+	p.c.RAM.MustWrite(0x4015, 0x0f)
+	p.c.RAM.MustWrite(0x4017, 0x40)
+
+	// The init routine receives the track number (zero based) in A and the
+	// region (0 for NTSC, 1 for PAL) in X
+	p.c.Reg.A = byte(track - 1)
+	p.c.Reg.X = 0
+	if p.nsf.Header.Region() == region.PAL {
+		p.c.Reg.X = 1
+	}
+	p.c.Reg.SP = 0xff
+	p.c.Reg.I = 1
+
+	_, err := p.runRoutine(p.nsf.Header.InitAddr)
+	return errors.Wrap(err, "Failed to run init routine")
+}
+
+// Step calls the NSF's play routine once, running the CPU and APU for a single
+// play period.
+func (p *Player) Step() error {
+	cycles, err := p.runRoutine(p.nsf.Header.PlayAddr)
+	if err != nil {
+		return errors.Wrap(err, "Failed to run play routine")
+	}
+
+	// Idle until the next play call, keeping the APU running
+	p.cycleDebt += p.playPeriod - float64(cycles)
+	for ; p.cycleDebt >= 1; p.cycleDebt-- {
+		p.a.Cycle()
+	}
+
+	// The CPU is idle between play calls, so DMC fetches made meanwhile
+	// don't stall the next routine
+	p.a.DMAStall()
+
+	return nil
+}
+
+// runRoutine calls a routine at addr using the mapper's trampoline, running
+// until the routine returns.
+//
+// runRoutine returns the amount of cycles the routine took.
+func (p *Player) runRoutine(addr int) (cycles int, err error) {
+	p.m.setTrampoline(addr)
+	p.c.Reg.PC = trampolineAddr
+
+	// The trampoline's JSR returns right after it
+	for p.c.Reg.PC != trampolineAddr+len(p.m.trampoline) {
+		c, err := p.c.ExecNext()
+		if err != nil {
+			return cycles, errors.Wrap(err, "Failed to execute next opcode")
+		}
+
+		for i := 0; i < c; i++ {
+			p.a.Cycle()
+		}
+
+		cycles += c
+		if cycles > maxRoutineCycles {
+			return cycles, errors.Errorf("Routine at $%04x didn't return",
+				addr)
+		}
+	}
+
+	return cycles, nil
+}
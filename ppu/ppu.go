@@ -6,6 +6,7 @@ import (
 	"image/color"
 
 	"github.com/m4ntis/bones/ines"
+	"github.com/m4ntis/bones/region"
 )
 
 // Displayer describes a place that the PPU outputs its frames to.
@@ -44,8 +45,11 @@ type PPU struct {
 	x        int
 	oddCycle bool
 
-	// Mirroring type
-	mirror int
+	timing timing
+
+	// Optional mapper capabilities, set when loading a ROM
+	fetchObserver   ines.PPUFetchObserver
+	renderingMapper ines.RenderingMapper
 
 	// Output
 	frame *frame
@@ -72,6 +76,8 @@ func New(disp Displayer) *PPU {
 
 		NMI: nmi,
 
+		timing: timings[region.NTSC],
+
 		frame: newFrame(),
 		disp:  disp,
 	}
@@ -81,7 +87,8 @@ func New(disp Displayer) *PPU {
 // ROM's reset vector.
 func (ppu *PPU) Load(rom *ines.ROM) {
 	ppu.VRAM.Mapper = rom.Mapper
-	ppu.mirror = rom.Header.Mirroring
+	ppu.fetchObserver, _ = rom.Mapper.(ines.PPUFetchObserver)
+	ppu.renderingMapper, _ = rom.Mapper.(ines.RenderingMapper)
 }
 
 //TODO: Take note of oamaddr when performing DMA
@@ -96,12 +103,16 @@ func (ppu *PPU) DMA(oamData [256]byte) {
 //
 // Cycle may cause the ppu to generate an NMI or output a frame to the display.
 func (ppu *PPU) Cycle() {
+	if ppu.x == 0 && ppu.scanline <= 240 && ppu.renderingMapper != nil {
+		ppu.startScanline()
+	}
+
 	if ppu.scanline >= 0 && ppu.scanline < 240 {
 		ppu.visibleScanlineCycle()
-	} else if ppu.scanline == 241 && ppu.x == 1 {
+	} else if ppu.scanline == ppu.timing.vblankScanline && ppu.x == 1 {
 		ppu.vblankBegin()
-	} else if ppu.scanline == 261 && ppu.x == 1 {
-		ppu.vblankEnd()
+	} else if ppu.scanline == ppu.preRenderScanline() {
+		ppu.preRenderScanlineCycle()
 	}
 
 	ppu.incCoords()
@@ -124,6 +135,31 @@ func (ppu *PPU) visibleScanlineCycle() {
 	}
 }
 
+// startScanline notifies the mapper of the scanline the PPU starts rendering.
+func (ppu *PPU) startScanline() {
+	scanline := ppu.scanline
+	if scanline == 240 || !ppu.renderingEnabled() {
+		scanline = -1
+	}
+
+	ppu.renderingMapper.RenderScanline(scanline, ppu.spriteHeight() == 16)
+}
+
+// preRenderScanlineCycle executes the ppu's logic for the pre-render scanline
+// (261 on NTSC, 311 on PAL and Dendy), which ends vblank.
+//
+// No sprites are rendered on the next scanline, but the sprite fetches are
+// still performed, as mappers may be watching them.
+func (ppu *PPU) preRenderScanlineCycle() {
+	if ppu.x == 1 {
+		ppu.vblankEnd()
+	}
+
+	if ppu.x >= 257 && ppu.x <= 320 && ppu.x%8 == 1 {
+		ppu.fetchDummySprite()
+	}
+}
+
 // vblankBegin sets vblank flags, publishes an NMI if nmi is enabled in PPUCTRL
 // and pushes a frame to display.
 func (ppu *PPU) vblankBegin() {
@@ -150,20 +186,29 @@ func (ppu *PPU) vblankEnd() {
 
 // incCoords increments ppu's coordinate parameters for next cycle.
 //
-// incCoords will skip from (339,261) to (0,0) on odd ppu frames.
+// On NTSC, incCoords will skip from (339,261) to (0,0) on odd ppu frames while
+// rendering is enabled.
 func (ppu *PPU) incCoords() {
 	ppu.x++
-	if ppu.x > 340 || (ppu.scanline == 261 && ppu.x == 339 || ppu.oddCycle) {
+	if ppu.x > 340 || (ppu.scanline == ppu.preRenderScanline() &&
+		ppu.x == 340 && ppu.oddCycle && ppu.timing.oddFrameSkip &&
+		ppu.renderingEnabled()) {
 		ppu.x = 0
 
 		ppu.scanline++
-		if ppu.scanline > 261 {
+		if ppu.scanline > ppu.preRenderScanline() {
 			ppu.scanline = 0
 			ppu.oddCycle = !ppu.oddCycle
 		}
 	}
 }
 
+// preRenderScanline returns the number of the frame's last scanline, which
+// prepares the first visible scanline.
+func (ppu *PPU) preRenderScanline() int {
+	return ppu.timing.scanlines - 1
+}
+
 // evaluateSprites fetches sprite date for next scanline's sprites during
 // visible scanlines.
 //
@@ -210,7 +255,7 @@ func (ppu *PPU) evaluateSprite() {
 	sprY := int(sprData[0])
 
 	// Check sprite in range of next scanline
-	if ppu.scanline >= sprY && ppu.scanline < sprY+8 {
+	if ppu.scanline >= sprY && ppu.scanline < sprY+ppu.spriteHeight() {
 		if ppu.foundSprCount < 8 {
 			// Copy sprite data from OAM to secondary OAM
 			copy(ppu.sOAM[ppu.foundSprCount*sprDataSize:(ppu.foundSprCount+1)*sprDataSize],
@@ -238,16 +283,27 @@ func (ppu *PPU) renderSprite() {
 
 	// Check whether this sprite slot is used for next frame
 	if ppu.foundSprCount >= renderedSprNum+1 {
-		// Determine pattern table number and address to fetch sprite data from
-		pt := int((ppu.Regs.ppuCtrl >> 3) & 1)
-		ptAddr := pt*ptSize + int(sprData[1])*16
-
 		// Calculate line of the sprite to be displayed on the scanline
 		sprLine := ppu.scanline - int(sprData[0])
 
+		// Determine pattern table number and address to fetch sprite data from
+		pt := int((ppu.Regs.ppuCtrl >> 3) & 1)
+		tile := int(sprData[1])
+		if ppu.spriteHeight() == 16 {
+			// 8x16 sprites select their pattern table by the tile number's
+			// low bit, the bottom half being the next tile
+			pt = tile & 1
+			tile &^= 1
+			if sprLine >= 8 {
+				tile++
+				sprLine -= 8
+			}
+		}
+		ptAddr := pt*ptSize + tile*16
+
 		// Fetch sprite data
-		dataLow := ppu.VRAM.Read(ptAddr + sprLine)
-		dataHigh := ppu.VRAM.Read(ptAddr + sprLine + 8)
+		dataLow := ppu.fetchPattern(ptAddr+sprLine, true)
+		dataHigh := ppu.fetchPattern(ptAddr+sprLine+8, true)
 
 		// Invert sprite if horizontal invert bit of attribute byte is off
 		if (sprData[2]>>6)&1 == 0 {
@@ -269,9 +325,72 @@ func (ppu *PPU) renderSprite() {
 		}
 	} else {
 		ppu.sprites[renderedSprNum] = nilSprite
+		ppu.fetchDummySprite()
 	}
 }
 
+// fetchDummySprite performs the pattern fetches of an unused sprite slot,
+// which fetch tile $ff from the sprite pattern table.
+func (ppu *PPU) fetchDummySprite() {
+	pt := int((ppu.Regs.ppuCtrl >> 3) & 1)
+	ptAddr := pt*ptSize + 0xff*16
+	if ppu.spriteHeight() == 16 {
+		ptAddr = pt1Addr + 0xfe*16
+	}
+
+	ppu.fetchPattern(ptAddr, true)
+	ppu.fetchPattern(ptAddr+8, true)
+}
+
+// spriteHeight returns the height of sprites, 8 or 16, according to bit 5 of
+// PPUCTRL.
+func (ppu *PPU) spriteHeight() int {
+	if ppu.Regs.ppuCtrl>>5&1 == 1 {
+		return 16
+	}
+	return 8
+}
+
+// fetchPattern reads a byte from a pattern table as part of rendering,
+// notifying the mapper of the fetch if it observes the PPU's fetches. sprite is
+// set for sprite fetches.
+//
+// The PPU doesn't fetch anything while rendering is disabled, so the mapper
+// is only notified while it is enabled.
+func (ppu *PPU) fetchPattern(addr int, sprite bool) byte {
+	var d byte
+	if ppu.renderingMapper != nil {
+		d = ppu.renderingMapper.FetchPattern(addr, sprite)
+	} else {
+		d = ppu.VRAM.Read(addr)
+	}
+
+	if ppu.fetchObserver != nil && ppu.renderingEnabled() {
+		ppu.fetchObserver.PPUFetch(addr)
+	}
+
+	return d
+}
+
+// fetchNametable reads a byte from a nametable or attribute table as part of
+// rendering the background.
+func (ppu *PPU) fetchNametable(addr int) byte {
+	if ppu.renderingMapper != nil {
+		d, ok := ppu.renderingMapper.FetchNametable(addr, ppu.x/8)
+		if ok {
+			return d
+		}
+	}
+
+	return ppu.VRAM.Read(addr)
+}
+
+// renderingEnabled returns whether either background or sprite rendering is
+// enabled in PPUMASK.
+func (ppu *PPU) renderingEnabled() bool {
+	return ppu.Regs.ppuMask&(3<<3) != 0
+}
+
 // calcPixelValue is called once per visible cycle (0 <= scanline < 240 &&
 // 0 <= x < 256) and calculates the pixel value.
 func (ppu *PPU) calcPixelValue() color.RGBA {
@@ -285,7 +404,7 @@ func (ppu *PPU) calcPixelValue() color.RGBA {
 		ppu.Regs.ppuStatus |= (1 << 6)
 	}
 
-	return Palette[paletteAddr]
+	return ppu.emphasize(Palette[paletteAddr])
 }
 
 // calcBgrValue calculates bgr value for current pixel.
@@ -303,10 +422,22 @@ func (ppu *PPU) calcBgrValue() (bgr int) {
 	scrolledX := ppu.x + ppu.Regs.xScroll
 	scrolledY := ppu.scanline + ppu.Regs.yScroll
 
+	// Scrolling past the edges of the nametable selected by PPUCTRL wraps
+	// around to the next nametable
+	ntNum := int(ppu.Regs.ppuCtrl) & 3
+	if scrolledX >= 256 {
+		ntNum ^= 1
+		scrolledX -= 256
+	}
+	if scrolledY >= 240 {
+		ntNum ^= 2
+		scrolledY -= 240
+	}
+
 	// Fetch byte from NT
-	baseNTAddr := getNTAddr(int(ppu.Regs.ppuCtrl)&3+scrolledX/256, ppu.mirror)
-	ntIdx := (scrolledY/8)*32 + scrolledX%0x100/8
-	byteFromNT := ppu.VRAM.Read(baseNTAddr + ntIdx)
+	baseNTAddr := nt0Addr + ntNum*ines.NametableSize
+	ntIdx := (scrolledY/8)*32 + scrolledX/8
+	byteFromNT := ppu.fetchNametable(baseNTAddr + ntIdx)
 
 	// Fetch pattern table address
 	basePTAddr := ppu.getPTAddr()
@@ -314,8 +445,8 @@ func (ppu *PPU) calcBgrValue() (bgr int) {
 
 	// Fetch pattern line from PT (Y coordinate)
 	pty := scrolledY % 8
-	ptLowByte := ppu.VRAM.Read(patternAddr + pty)
-	ptHighByte := ppu.VRAM.Read(patternAddr + pty + 8)
+	ptLowByte := ppu.fetchPattern(patternAddr+pty, false)
+	ptHighByte := ppu.fetchPattern(patternAddr+pty+8, false)
 
 	// Fetch pixel data from pattern line (X coordinate)
 	ptx := scrolledX % 8
@@ -327,8 +458,8 @@ func (ppu *PPU) calcBgrValue() (bgr int) {
 
 	// Fetch byte from AT
 	baseATAddr := getATAddr(baseNTAddr)
-	atIdx := (scrolledY/32)*8 + scrolledX%0x100/32
-	byteFromAT := ppu.VRAM.Read(baseATAddr + atIdx)
+	atIdx := (scrolledY/32)*8 + scrolledX/32
+	byteFromAT := ppu.fetchNametable(baseATAddr + atIdx)
 
 	// Calculate which 2 bits of the byte from AT are relevant
 	atQuarter := scrolledX%32/16 + scrolledY%32/16<<1
@@ -339,25 +470,6 @@ func (ppu *PPU) calcBgrValue() (bgr int) {
 	return int(bgrLow + (bgrHigh&3)<<2)
 }
 
-// getATAddr returns the base address for nametable 1~4 based on a nametable
-// number and a number representing mirroring mode.
-func getNTAddr(ntNum int, mirroring int) int {
-	// Assuming either horizontal or vertical mirroring
-	if mirroring == ines.HorizontalMirroring {
-		if ntNum == 0 || ntNum == 1 {
-			return nt0Addr
-		} else {
-			return nt2Addr
-		}
-	} else {
-		if ntNum == 0 || ntNum == 2 {
-			return nt0Addr
-		} else {
-			return nt1Addr
-		}
-	}
-}
-
 // getPTAddr returns the base address for a pattern table according to bit 4 of
 // PPUCTRL.
 func (ppu *PPU) getPTAddr() int {
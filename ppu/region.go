@@ -0,0 +1,76 @@
+package ppu
+
+import (
+	"image/color"
+
+	"github.com/m4ntis/bones/region"
+)
+
+// emphasisAttenuation is the factor by which colour emphasis dims the colour
+// components that aren't emphasized
+const emphasisAttenuation = 0.75
+
+// timing holds the region dependent parts of the PPU's frame.
+type timing struct {
+	// scanlines is the amount of scanlines per frame, the last of which is
+	// the pre-render scanline
+	scanlines int
+	// vblankScanline is the scanline vblank starts on
+	vblankScanline int
+	// oddFrameSkip is set if odd frames skip a cycle while rendering
+	oddFrameSkip bool
+	// swapEmphasis is set if the red and green emphasis bits of PPUMASK are
+	// swapped
+	swapEmphasis bool
+}
+
+var timings = map[region.Region]timing{
+	region.NTSC: {
+		scanlines:      262,
+		vblankScanline: 241,
+		oddFrameSkip:   true,
+	},
+	region.PAL: {
+		scanlines:      312,
+		vblankScanline: 241,
+		swapEmphasis:   true,
+	},
+	// The Dendy starts vblank 50 scanlines late, so that it has as many CPU
+	// cycles between the NMI and the start of rendering as on NTSC
+	region.Dendy: {
+		scanlines:      312,
+		vblankScanline: 291,
+		swapEmphasis:   true,
+	},
+}
+
+// SetRegion sets the PPU's frame timing to a region's.
+func (ppu *PPU) SetRegion(r region.Region) {
+	ppu.timing = timings[r]
+}
+
+// emphasize applies PPUMASK's colour emphasis bits to a colour, dimming the
+// components that aren't emphasized.
+func (ppu *PPU) emphasize(c color.RGBA) color.RGBA {
+	emphasis := ppu.Regs.ppuMask >> 5
+	if emphasis == 0 {
+		return c
+	}
+
+	red, green, blue := emphasis&1, emphasis>>1&1, emphasis>>2
+	if ppu.timing.swapEmphasis {
+		red, green = green, red
+	}
+
+	if red == 0 {
+		c.R = byte(float64(c.R) * emphasisAttenuation)
+	}
+	if green == 0 {
+		c.G = byte(float64(c.G) * emphasisAttenuation)
+	}
+	if blue == 0 {
+		c.B = byte(float64(c.B) * emphasisAttenuation)
+	}
+
+	return c
+}
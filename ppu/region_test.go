@@ -0,0 +1,93 @@
+package ppu
+
+import (
+	"image"
+	"testing"
+
+	"github.com/m4ntis/bones/ines"
+	"github.com/m4ntis/bones/region"
+)
+
+// frameCounter is a Displayer counting the frames it receives.
+type frameCounter struct {
+	frames int
+}
+
+func (d *frameCounter) Display(image.Image) {
+	d.frames++
+}
+
+// newTestROM returns an NROM of empty PRG and CHR ROM.
+func newTestROM() *ines.ROM {
+	header := ines.INESHeader{PrgROMSize: 1, ChrROMSize: 1}
+	m, err := ines.NewMapper(header)
+	if err != nil {
+		panic(err)
+	}
+	m.Populate(make([]ines.PrgROMPage, 1), make([]ines.ChrROMPage, 1))
+
+	return &ines.ROM{Header: header, Mapper: m}
+}
+
+func TestRegionTiming(t *testing.T) {
+	tests := []struct {
+		r              region.Region
+		scanlines      int
+		vblankScanline int
+	}{
+		{region.NTSC, 262, 241},
+		{region.PAL, 312, 241},
+		{region.Dendy, 312, 291},
+	}
+
+	for _, test := range tests {
+		disp := &frameCounter{}
+		ppu := New(disp)
+		ppu.Load(newTestROM())
+		ppu.SetRegion(test.r)
+
+		// Run until vblank starts, on the second cycle of its scanline
+		cycles := 0
+		for disp.frames == 0 {
+			ppu.Cycle()
+			cycles++
+		}
+		if want := test.vblankScanline*341 + 2; cycles != want {
+			t.Errorf("%v: vblank started after %d cycles, want %d", test.r,
+				cycles, want)
+		}
+		if ppu.Regs.ppuStatus>>7 != 1 {
+			t.Errorf("%v: PPUSTATUS vblank flag not set", test.r)
+		}
+
+		// The vblank flag is cleared on the second cycle of the pre-render
+		// scanline
+		vblankScanlines := 0
+		for ppu.Regs.ppuStatus>>7 == 1 {
+			if ppu.x == 0 {
+				vblankScanlines++
+			}
+			ppu.Cycle()
+		}
+		if want := test.scanlines - 1 - test.vblankScanline; vblankScanlines !=
+			want {
+			t.Errorf("%v: vblank lasted %d scanlines, want %d", test.r,
+				vblankScanlines, want)
+		}
+
+		// A whole frame, from the second vblank to the third, doesn't skip a
+		// cycle while rendering is disabled
+		for disp.frames < 2 {
+			ppu.Cycle()
+		}
+		cycles = 0
+		for disp.frames < 3 {
+			ppu.Cycle()
+			cycles++
+		}
+		if want := test.scanlines * 341; cycles != want {
+			t.Errorf("%v: frame took %d cycles, want %d", test.r, cycles,
+				want)
+		}
+	}
+}
@@ -27,8 +27,11 @@ const (
 // VRAM holds the Ricoh 2A03's 16kb (64 when mirrored) of on board memory.
 //
 // All VRAM accessing methods contain logic for mirrored address translation.
+// The nametables are mapped by the mapper, either to the PPU's 2k of CIRAM or
+// to the cartridge.
 type VRAM struct {
-	data [RAMSize]byte
+	data  [RAMSize]byte
+	ciram [2 * ines.NametableSize]byte
 
 	Mapper ines.Mapper
 }
@@ -65,6 +68,16 @@ func (v *VRAM) Read(addr int) byte {
 		return d
 	}
 
+	// addr is a NT address
+	if addr < tablesMirrorAddr {
+		ciramAddr, ok := v.ciramAddr(addr)
+		if !ok {
+			d, _ := v.Mapper.Read(addr)
+			return d
+		}
+		return v.ciram[ciramAddr]
+	}
+
 	return v.data[addr]
 }
 
@@ -78,5 +91,28 @@ func (v *VRAM) Write(addr int, d byte) {
 		return
 	}
 
+	// addr is a NT address
+	if addr < tablesMirrorAddr {
+		ciramAddr, ok := v.ciramAddr(addr)
+		if !ok {
+			v.Mapper.Write(addr, d)
+			return
+		}
+		v.ciram[ciramAddr] = d
+		return
+	}
+
 	v.data[addr] = d
 }
+
+// ciramAddr returns the offset in CIRAM of a nametable address, according to
+// the mapper's nametable mapping. ok is false if the nametable is mapped to the
+// cartridge.
+func (v *VRAM) ciramAddr(addr int) (ciramAddr int, ok bool) {
+	page := v.Mapper.Nametables()[(addr-nt0Addr)/ines.NametableSize]
+	if page == ines.CartridgeNametable {
+		return 0, false
+	}
+
+	return page*ines.NametableSize + addr%ines.NametableSize, true
+}
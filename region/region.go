@@ -0,0 +1,80 @@
+// Package region defines the NES's regional variants, which differ in their
+// clock rates and video timing
+package region
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Region identifies a regional variant of the console.
+type Region int
+
+const (
+	// NTSC is the North American and Japanese console, with a 2C02 PPU
+	NTSC Region = iota
+	// PAL is the European and Australian console, with a 2C07 PPU
+	PAL
+	// Dendy is the Russian famiclone, which times its video like PAL with
+	// NTSC's CPU/PPU clock ratio
+	Dendy
+)
+
+var regionNames = map[Region]string{
+	NTSC:  "ntsc",
+	PAL:   "pal",
+	Dendy: "dendy",
+}
+
+// Parse returns the region named name, case insensitive.
+func Parse(name string) (Region, error) {
+	for r, n := range regionNames {
+		if strings.ToLower(name) == n {
+			return r, nil
+		}
+	}
+
+	return NTSC, errors.Errorf("Unknown region %s, expected ntsc, pal or dendy",
+		name)
+}
+
+func (r Region) String() string {
+	name, ok := regionNames[r]
+	if !ok {
+		return "unknown"
+	}
+	return name
+}
+
+// CPUClockRate returns the CPU's clock rate in Hz, which the APU runs at.
+func (r Region) CPUClockRate() float64 {
+	switch r {
+	case PAL:
+		return 1662607
+	case Dendy:
+		return 1773448
+	default:
+		return 1789773
+	}
+}
+
+// PPUClockRatio returns the amount of PPU cycles per CPU cycle as a fraction,
+// being 3 on NTSC and Dendy, and 3.2 on PAL.
+func (r Region) PPUClockRatio() (num, den int) {
+	if r == PAL {
+		return 16, 5
+	}
+	return 3, 1
+}
+
+// FrameRate returns the amount of frames the PPU outputs per second.
+//
+// PAL and Dendy consoles share their frame rate, as the Dendy's faster CPU
+// makes up for its lower CPU/PPU clock ratio.
+func (r Region) FrameRate() float64 {
+	if r == NTSC {
+		return 60.0988
+	}
+	return 50.0070
+}